@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/nguyenluan2001/csv-query/pkg"
 )
@@ -37,8 +38,11 @@ func main() {
 	// 		ORDER BY min_salary
 	// 		`
 	databasePathPtr := flag.String("d", ".", "Path to directory that stored your csv files")
+	nullMarkersPtr := flag.String("null", strings.Join(pkg.NullMarkers, ","), "Comma separated cell values treated as NULL, e.g. \",NULL,\\N,NA\"")
 	flag.Parse()
 
+	pkg.SetNullMarkers(strings.Split(*nullMarkersPtr, ","))
+
 	csvql := pkg.NewQuery("", *databasePathPtr)
 	csvql.Repl()
 }
@@ -2,16 +2,19 @@ module github.com/nguyenluan2001/csv-query
 
 go 1.24.4
 
-require github.com/kr/pretty v0.3.1
+require (
+	github.com/cbroglie/mustache v1.4.0
+	github.com/chzyer/readline v1.5.1
+	github.com/jessevdk/go-flags v1.6.1
+	github.com/kr/pretty v0.3.1
+	github.com/olekukonko/tablewriter v1.1.2
+)
 
 require (
-	github.com/cbroglie/mustache v1.4.0 // indirect
-	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/clipperhouse/displaywidth v0.6.0 // indirect
 	github.com/clipperhouse/stringish v0.1.1 // indirect
 	github.com/clipperhouse/uax29/v2 v2.3.0 // indirect
 	github.com/fatih/color v1.15.0 // indirect
-	github.com/jessevdk/go-flags v1.6.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
@@ -19,7 +22,6 @@ require (
 	github.com/olekukonko/cat v0.0.0-20250911104152-50322a0618f6 // indirect
 	github.com/olekukonko/errors v1.1.0 // indirect
 	github.com/olekukonko/ll v0.1.3 // indirect
-	github.com/olekukonko/tablewriter v1.1.2 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
 )
@@ -3,79 +3,484 @@ package pkg
 import (
 	"fmt"
 	"slices"
+	"strings"
 )
 
-func Sum(collector []string) string {
-	sum := 0
-	for _, val := range collector {
-		valNumber, _ := StringToInt(Stringify(val))
-		sum += valNumber
-	}
-	return Stringify(sum)
+// Struct of aggregate function call, e.g. SUM(salary), COUNT(DISTINCT id) or STRING_AGG(name, ', ' ORDER BY name)
+type AggregateExpr struct {
+	Name     Token
+	Arg      interface{}
+	Params   []Expr          // Constant arguments after Arg, e.g. separator or fraction
+	OrderBy  []OrderBySingle // Order of values fed into order-sensitive function
+	Distinct bool
 }
 
-func Count(collector []string) string {
-	return Stringify(len(collector))
+// Definition of aggregate function, arity counts aggregated argument and constant parameters
+type AggregateFunction struct {
+	Name        string
+	MinArgs     int
+	MaxArgs     int
+	IsOrdered   bool // Result depends on order of values, so ORDER BY is allowed within call
+	Accumulator func(params []Value) (Accumulator, error)
 }
 
-func Average(collector []string) string {
-	sum := 0
-	for _, val := range collector {
-		valNumber, _ := StringToInt(Stringify(val))
-		sum += valNumber
+var aggregateFunctions = map[string]AggregateFunction{}
+
+// Add aggregate function to registry, lookup is case-insensitive
+func RegisterAggregateFunction(function AggregateFunction) {
+	aggregateFunctions[function.Name] = function
+}
+
+func init() {
+	RegisterAggregateFunction(AggregateFunction{Name: "SUM", MinArgs: 1, MaxArgs: 1, Accumulator: NewSumAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "COUNT", MinArgs: 1, MaxArgs: 1, Accumulator: NewCountAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "AVG", MinArgs: 1, MaxArgs: 1, Accumulator: NewAverageAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "MAX", MinArgs: 1, MaxArgs: 1, Accumulator: NewMaxAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "MIN", MinArgs: 1, MaxArgs: 1, Accumulator: NewMinAccumulator})
+
+	// === Statistical ===
+	RegisterAggregateFunction(AggregateFunction{Name: "STDDEV", MinArgs: 1, MaxArgs: 1, Accumulator: NewStddevSampAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "STDDEV_SAMP", MinArgs: 1, MaxArgs: 1, Accumulator: NewStddevSampAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "STDDEV_POP", MinArgs: 1, MaxArgs: 1, Accumulator: NewStddevPopAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "VARIANCE", MinArgs: 1, MaxArgs: 1, Accumulator: NewVarSampAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "VAR_SAMP", MinArgs: 1, MaxArgs: 1, Accumulator: NewVarSampAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "VAR_POP", MinArgs: 1, MaxArgs: 1, Accumulator: NewVarPopAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "MEDIAN", MinArgs: 1, MaxArgs: 1, Accumulator: NewMedianAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "PERCENTILE_CONT", MinArgs: 2, MaxArgs: 2, Accumulator: NewPercentileContAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "PERCENTILE_DISC", MinArgs: 2, MaxArgs: 2, Accumulator: NewPercentileDiscAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "MODE", MinArgs: 1, MaxArgs: 1, Accumulator: NewModeAccumulator})
+
+	// === Values in order ===
+	RegisterAggregateFunction(AggregateFunction{Name: "STRING_AGG", MinArgs: 2, MaxArgs: 2, IsOrdered: true, Accumulator: NewStringAggAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "GROUP_CONCAT", MinArgs: 1, MaxArgs: 2, IsOrdered: true, Accumulator: NewStringAggAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "BOOL_AND", MinArgs: 1, MaxArgs: 1, Accumulator: NewBoolAndAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "BOOL_OR", MinArgs: 1, MaxArgs: 1, Accumulator: NewBoolOrAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "FIRST", MinArgs: 1, MaxArgs: 1, IsOrdered: true, Accumulator: NewFirstAccumulator})
+	RegisterAggregateFunction(AggregateFunction{Name: "LAST", MinArgs: 1, MaxArgs: 1, IsOrdered: true, Accumulator: NewLastAccumulator})
+}
+
+func GetAggregateFunction(name string) (AggregateFunction, bool) {
+	function, ok := aggregateFunctions[strings.ToUpper(name)]
+	return function, ok
+}
+
+// Register prefix for aggregate function call
+func (p *Parser) RegisterAggregatePrefix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		nud: func(p *Parser) (Expr, error) {
+			return p.ParseAggregateCall(p.tokens[p.pointer-1])
+		},
 	}
-	return Stringify(sum / len(collector))
 }
 
-func Max(collector []string) string {
-	slices.SortFunc(collector, func(val1, val2 string) int {
-		return CompareProxy(val2, val1)
-	})
-	if len(collector) == 0 {
-		return ""
+// Handle parse aggregate function call after its name:
+// "(" [DISTINCT] arg {"," param} [ORDER BY expr [ASC | DESC] {"," ...}] ")", or COUNT(*)
+func (p *Parser) ParseAggregateCall(name Token) (Expr, error) {
+	function, ok := GetAggregateFunction(Stringify(name.Value))
+	if !ok {
+		return nil, NewSyntaxError(name, fmt.Sprintf("unknown aggregate function %v", name.Value))
+	}
+	name.Value = function.Name
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+
+	agg := AggregateExpr{Name: name}
+	if p.current.Type == TokenStar {
+		star := p.current
+		if function.Name != "COUNT" {
+			return nil, NewSyntaxError(star, fmt.Sprintf("function %v does not accept *", function.Name))
+		}
+		p.Advance()
+		if _, err := p.Expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		agg.Arg = star
+		return p.ParseAggregateWindow(agg)
+	}
+
+	if p.current.Type == TokenDistinct {
+		agg.Distinct = true
+		p.Advance()
 	}
-	return Stringify(collector[0])
+	args := []Expr{}
+	for {
+		arg, err := p.ParseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.current.Type != TokenComma {
+			break
+		}
+		p.Advance()
+	}
+	if p.current.Type == TokenOrderBy {
+		if !function.IsOrdered {
+			return nil, NewSyntaxError(p.current, fmt.Sprintf("function %v does not accept ORDER BY", function.Name))
+		}
+		p.Advance()
+		orderBy, err := p.ParseOrderByList()
+		if err != nil {
+			return nil, err
+		}
+		agg.OrderBy = orderBy
+	}
+	if _, err := p.Expect(TokenRParen); err != nil {
+		return nil, err
+	}
+
+	if len(args) < function.MinArgs || len(args) > function.MaxArgs {
+		return nil, NewSyntaxError(name, fmt.Sprintf("function %v expects %v, got %d", function.Name, FormatArity(function.MinArgs, function.MaxArgs), len(args)))
+	}
+	agg.Arg = args[0]
+	agg.Params = args[1:]
+	// Check constant parameters once at parse time, e.g. percentile fraction
+	params, err := AggregateParams(agg)
+	if err == nil {
+		_, err = function.Accumulator(params)
+	}
+	if err != nil {
+		return nil, NewSyntaxError(name, err.Error())
+	}
+	return p.ParseAggregateWindow(agg)
 }
 
-func Min(collector []string) string {
-	slices.SortFunc(collector, func(val1, val2 string) int {
-		return CompareProxy(val1, val2)
-	})
-	if len(collector) == 0 {
-		return ""
+// Aggregate call followed by OVER is window function, otherwise its arguments can not contain other aggregates or windows.
+// Arguments of window aggregate may contain aggregates, which are computed by GROUP BY first
+func (p *Parser) ParseAggregateWindow(agg AggregateExpr) (Expr, error) {
+	for _, child := range ExprChildren(agg) {
+		if ContainsWindow(child) {
+			return nil, NewSyntaxError(agg.Name, "window function calls can not be used in aggregate function arguments")
+		}
 	}
-	return Stringify(collector[0])
+	if p.current.Type == TokenOver {
+		return p.ParseWindow(WindowExpr{Name: agg.Name, Aggregate: &agg})
+	}
+	for _, child := range ExprChildren(agg) {
+		if ContainsAggregate(child) {
+			return nil, NewSyntaxError(agg.Name, "aggregate function calls can not be nested")
+		}
+	}
+	return agg, nil
 }
 
-func GetAggregateFnName(token Column) string {
-	switch token.Type {
-	case TokenSum:
-		{
-			return "SUM"
+// Values of constant parameters of aggregate function call
+func AggregateParams(agg AggregateExpr) ([]Value, error) {
+	params := []Value{}
+	for i, param := range agg.Params {
+		if len(ExprColumns(param)) > 0 {
+			return nil, fmt.Errorf("argument %d of %v must be a constant", i+2, agg.Name.Value)
 		}
-	case TokenCount:
-		{
-			return "COUNT"
+		value, err := Eval(param, nil, map[string]int{})
+		if err != nil {
+			return nil, err
 		}
-	case TokenMax:
-		{
-			return "MAX"
+		params = append(params, value)
+	}
+	return params, nil
+}
+
+// SQL text of aggregate function call
+func AggregateString(agg AggregateExpr) string {
+	args := []string{ExprString(agg.Arg)}
+	for _, param := range agg.Params {
+		args = append(args, ExprString(param))
+	}
+	text := strings.Join(args, ", ")
+	if agg.Distinct {
+		text = "DISTINCT " + text
+	}
+	if len(agg.OrderBy) > 0 {
+		text += " " + OrderByString(agg.OrderBy)
+	}
+	return fmt.Sprintf("%v(%v)", agg.Name.Value, text)
+}
+
+// Name of aggregate result within group row, same calls share one result
+func AggregateKey(agg AggregateExpr) string {
+	return "#" + ExprString(agg)
+}
+
+// Whether aggregate is COUNT(*), which counts rows instead of non-NULL values
+func IsCountStar(agg AggregateExpr) bool {
+	token, isToken := agg.Arg.(Token)
+	return isToken && token.Type == TokenStar
+}
+
+// Get value of aggregate argument for row, every row counts for COUNT(*)
+func EvalAggregateArg(agg AggregateExpr, row []string, headerIndex map[string]int) (Value, error) {
+	if IsCountStar(agg) {
+		return BoolValue(true), nil
+	}
+	return Eval(agg.Arg, row, headerIndex)
+}
+
+// Add value of row into accumulator, ordered accumulator also gets ORDER BY keys of row
+func AccumulateRow(accumulator Accumulator, agg AggregateExpr, row []string, headerIndex map[string]int) error {
+	value, err := EvalAggregateArg(agg, row, headerIndex)
+	if err != nil {
+		return err
+	}
+	ordered, isOrdered := accumulator.(*OrderedAccumulator)
+	if !isOrdered {
+		return accumulator.Add(value)
+	}
+	keys := []Value{}
+	for _, condition := range agg.OrderBy {
+		key, err := Eval(condition.Expr, row, headerIndex)
+		if err != nil {
+			return err
 		}
-	case TokenMin:
-		{
-			return "MIN"
+		keys = append(keys, key)
+	}
+	ordered.AddWithKeys(value, keys)
+	return nil
+}
+
+// Get result of aggregate function call from group row
+func ComputeAggregate(agg AggregateExpr, row []string, headerIndex map[string]int) (Value, error) {
+	fieldIdx, ok := headerIndex[AggregateKey(agg)]
+	if !ok || fieldIdx >= len(row) {
+		return NullValue(), fmt.Errorf("Aggregate function %v is not allowed here", ExprString(agg))
+	}
+	return CellValue(row[fieldIdx]), nil
+}
+
+// Whether expression contains aggregate function call
+func ContainsAggregate(expr interface{}) bool {
+	return len(CollectAggregates(expr)) > 0
+}
+
+// Aggregate function calls within expression
+func CollectAggregates(expr interface{}) []AggregateExpr {
+	if agg, isAggregate := expr.(AggregateExpr); isAggregate {
+		return []AggregateExpr{agg}
+	}
+	aggregates := []AggregateExpr{}
+	for _, child := range ExprChildren(expr) {
+		if child != nil {
+			aggregates = append(aggregates, CollectAggregates(child)...)
 		}
-	case TokenAverage:
-		{
-			return "AVG"
+	}
+	return aggregates
+}
+
+// Running state of aggregate function over values of one group.
+// Aggregates skip NULL values. Over no values COUNT gives 0, others NULL
+type Accumulator interface {
+	Add(value Value) error
+	Result() Value
+}
+
+func NewAccumulator(agg AggregateExpr) (Accumulator, error) {
+	function, ok := GetAggregateFunction(Stringify(agg.Name.Value))
+	if !ok {
+		return nil, fmt.Errorf("Unsupported aggregate function %v", agg.Name.Value)
+	}
+	params, err := AggregateParams(agg)
+	if err != nil {
+		return nil, err
+	}
+	accumulator, err := function.Accumulator(params)
+	if err != nil {
+		return nil, err
+	}
+	if agg.Distinct {
+		accumulator = &DistinctAccumulator{inner: accumulator, seen: map[string]bool{}}
+	}
+	if len(agg.OrderBy) > 0 {
+		accumulator = &OrderedAccumulator{inner: accumulator, conditions: agg.OrderBy}
+	}
+	return accumulator, nil
+}
+
+func NewSumAccumulator(params []Value) (Accumulator, error) {
+	return &SumAccumulator{sum: NullValue()}, nil
+}
+
+func NewCountAccumulator(params []Value) (Accumulator, error) {
+	return &CountAccumulator{}, nil
+}
+
+func NewAverageAccumulator(params []Value) (Accumulator, error) {
+	return &AverageAccumulator{sum: IntValue(0)}, nil
+}
+
+func NewMaxAccumulator(params []Value) (Accumulator, error) {
+	return &ExtremeAccumulator{extreme: NullValue(), sign: 1}, nil
+}
+
+func NewMinAccumulator(params []Value) (Accumulator, error) {
+	return &ExtremeAccumulator{extreme: NullValue(), sign: -1}, nil
+}
+
+// Pass each distinct value once to inner accumulator. Values are keyed by their text like GROUP BY and
+// SELECT DISTINCT, so 1 and 1.0 are counted apart although 1 = 1.0
+type DistinctAccumulator struct {
+	inner Accumulator
+	seen  map[string]bool
+}
+
+func (acc *DistinctAccumulator) Add(value Value) error {
+	key := GroupKey([]Value{value})
+	if acc.seen[key] {
+		return nil
+	}
+	acc.seen[key] = true
+	return acc.inner.Add(value)
+}
+
+func (acc *DistinctAccumulator) Result() Value {
+	return acc.inner.Result()
+}
+
+// Value of order-sensitive aggregate with ORDER BY keys of its row
+type OrderedValue struct {
+	Value Value
+	Keys  []Value
+}
+
+// Feed values into inner accumulator sorted by ORDER BY keys of their rows once result is needed.
+// Only used for order-sensitive functions, whose accumulators accept any value
+type OrderedAccumulator struct {
+	inner      Accumulator
+	conditions []OrderBySingle
+	values     []OrderedValue
+	isDone     bool
+}
+
+func (acc *OrderedAccumulator) Add(value Value) error {
+	acc.AddWithKeys(value, make([]Value, len(acc.conditions)))
+	return nil
+}
+
+func (acc *OrderedAccumulator) AddWithKeys(value Value, keys []Value) {
+	acc.values = append(acc.values, OrderedValue{Value: value, Keys: keys})
+}
+
+func (acc *OrderedAccumulator) Result() Value {
+	if !acc.isDone {
+		acc.isDone = true
+		slices.SortStableFunc(acc.values, func(value1, value2 OrderedValue) int {
+			return OrderByKeysComparator(acc.conditions, SortableRow{Keys: value1.Keys}, SortableRow{Keys: value2.Keys})
+		})
+		for _, value := range acc.values {
+			acc.inner.Add(value.Value)
 		}
-	default:
-		{
-			return ""
+	}
+	return acc.inner.Result()
+}
+
+// Get numeric value for aggregate, numeric strings are coerced
+func AggregateNumber(name string, value Value) (Value, error) {
+	number := CoerceNumeric(value)
+	if !number.IsNumeric() {
+		return NullValue(), fmt.Errorf("%v expects number, got %v '%v'", name, value.Kind, value.String())
+	}
+	return number, nil
+}
+
+type SumAccumulator struct {
+	sum Value
+}
+
+func (acc *SumAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	number, err := AggregateNumber("SUM", value)
+	if err != nil {
+		return err
+	}
+	if acc.sum.IsNull() {
+		acc.sum = number
+		return nil
+	}
+	acc.sum, err = AddValues(acc.sum, number)
+	return err
+}
+
+func (acc *SumAccumulator) Result() Value {
+	return acc.sum
+}
+
+type CountAccumulator struct {
+	count int64
+}
+
+func (acc *CountAccumulator) Add(value Value) error {
+	if !value.IsNull() {
+		acc.count++
+	}
+	return nil
+}
+
+func (acc *CountAccumulator) Result() Value {
+	return IntValue(acc.count)
+}
+
+// Integers and decimals are summed exactly. Once a float is seen the running mean is
+// updated instead of summing, which keeps large float sums from overflowing or losing precision
+type AverageAccumulator struct {
+	sum     Value
+	mean    float64
+	count   int64
+	isFloat bool
+}
+
+func (acc *AverageAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	number, err := AggregateNumber("AVG", value)
+	if err != nil {
+		return err
+	}
+	acc.count++
+	if number.Kind == KindFloat && !acc.isFloat {
+		acc.isFloat = true
+		if acc.count > 1 {
+			acc.mean = acc.sum.AsFloat() / float64(acc.count-1)
 		}
 	}
+	if acc.isFloat {
+		acc.mean += (number.AsFloat() - acc.mean) / float64(acc.count)
+		return nil
+	}
+	acc.sum, err = AddValues(acc.sum, number)
+	return err
+}
+
+func (acc *AverageAccumulator) Result() Value {
+	if acc.count == 0 {
+		return NullValue()
+	}
+	if acc.isFloat {
+		return FloatValue(acc.mean)
+	}
+	average, _ := DivideValues(acc.sum, IntValue(acc.count))
+	return average
+}
+
+// MAX when sign is 1, MIN when sign is -1
+type ExtremeAccumulator struct {
+	extreme Value
+	sign    int
+}
+
+func (acc *ExtremeAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	cmp, isComparable := CompareValues(value, acc.extreme)
+	if !isComparable || cmp*acc.sign > 0 {
+		acc.extreme = value
+	}
+	return nil
 }
 
-func GenerateAggregateColumnName(token Column) string {
-	return fmt.Sprintf("%v_%v", GetAggregateFnName(token), token.Value)
+func (acc *ExtremeAccumulator) Result() Value {
+	return acc.extreme
 }
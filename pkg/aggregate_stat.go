@@ -0,0 +1,196 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+)
+
+func NewStddevSampAccumulator(params []Value) (Accumulator, error) {
+	return &VarianceAccumulator{name: "STDDEV", isSample: true, isStddev: true}, nil
+}
+
+func NewStddevPopAccumulator(params []Value) (Accumulator, error) {
+	return &VarianceAccumulator{name: "STDDEV_POP", isStddev: true}, nil
+}
+
+func NewVarSampAccumulator(params []Value) (Accumulator, error) {
+	return &VarianceAccumulator{name: "VARIANCE", isSample: true}, nil
+}
+
+func NewVarPopAccumulator(params []Value) (Accumulator, error) {
+	return &VarianceAccumulator{name: "VAR_POP"}, nil
+}
+
+// Integers and decimals are summed exactly, so variance has no cancellation error. Once a float is seen
+// Welford's online algorithm is used instead, which avoids cancellation of naive sum of squares.
+// Sample variance needs at least 2 values, population variance at least 1
+type VarianceAccumulator struct {
+	name       string
+	isSample   bool
+	isStddev   bool
+	count      int64
+	sum        big.Rat
+	sumSquares big.Rat
+	isFloat    bool
+	mean       float64
+	m2         float64
+}
+
+func (acc *VarianceAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	number, err := AggregateNumber(acc.name, value)
+	if err != nil {
+		return err
+	}
+	if number.Kind == KindFloat && !acc.isFloat {
+		acc.isFloat = true
+		acc.mean, acc.m2 = 0, 0
+		if acc.count > 0 {
+			acc.mean, _ = new(big.Rat).Quo(&acc.sum, big.NewRat(acc.count, 1)).Float64()
+			acc.m2, _ = acc.ExactM2().Float64()
+		}
+	}
+	acc.count++
+	if acc.isFloat {
+		x := number.AsFloat()
+		delta := x - acc.mean
+		acc.mean += delta / float64(acc.count)
+		acc.m2 += delta * (x - acc.mean)
+		return nil
+	}
+	x := number.AsRat()
+	acc.sum.Add(&acc.sum, x)
+	acc.sumSquares.Add(&acc.sumSquares, new(big.Rat).Mul(x, x))
+	return nil
+}
+
+// Sum of squared differences from mean: sumSquares - sum^2 / count
+func (acc *VarianceAccumulator) ExactM2() *big.Rat {
+	m2 := new(big.Rat).Mul(&acc.sum, &acc.sum)
+	m2.Quo(m2, big.NewRat(acc.count, 1))
+	return m2.Sub(&acc.sumSquares, m2)
+}
+
+func (acc *VarianceAccumulator) Result() Value {
+	divisor := acc.count
+	if acc.isSample {
+		divisor--
+	}
+	if divisor <= 0 {
+		return NullValue()
+	}
+	m2 := acc.m2
+	if !acc.isFloat {
+		m2, _ = acc.ExactM2().Float64()
+	}
+	variance := m2 / float64(divisor)
+	if acc.isStddev {
+		return FloatValue(math.Sqrt(variance))
+	}
+	return FloatValue(variance)
+}
+
+func NewMedianAccumulator(params []Value) (Accumulator, error) {
+	return &PercentileAccumulator{name: "MEDIAN", fraction: 0.5, isContinuous: true}, nil
+}
+
+func NewPercentileContAccumulator(params []Value) (Accumulator, error) {
+	return NewPercentileAccumulator("PERCENTILE_CONT", params[0], true)
+}
+
+func NewPercentileDiscAccumulator(params []Value) (Accumulator, error) {
+	return NewPercentileAccumulator("PERCENTILE_DISC", params[0], false)
+}
+
+func NewPercentileAccumulator(name string, fraction Value, isContinuous bool) (Accumulator, error) {
+	fraction = CoerceNumeric(fraction)
+	if !fraction.IsNumeric() || fraction.AsFloat() < 0 || fraction.AsFloat() > 1 {
+		return nil, fmt.Errorf("function %v expects fraction between 0 and 1, got %v", name, fraction.String())
+	}
+	return &PercentileAccumulator{name: name, fraction: fraction.AsFloat(), isContinuous: isContinuous}, nil
+}
+
+// PERCENTILE_CONT interpolates between the nearest numbers, PERCENTILE_DISC picks the first value
+// whose position reaches the fraction. Values are kept until result is needed
+type PercentileAccumulator struct {
+	name         string
+	fraction     float64
+	isContinuous bool
+	values       []Value
+}
+
+func (acc *PercentileAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	if acc.isContinuous {
+		number, err := AggregateNumber(acc.name, value)
+		if err != nil {
+			return err
+		}
+		value = number
+	}
+	acc.values = append(acc.values, value)
+	return nil
+}
+
+func (acc *PercentileAccumulator) Result() Value {
+	if len(acc.values) == 0 {
+		return NullValue()
+	}
+	slices.SortStableFunc(acc.values, func(value1, value2 Value) int {
+		cmp, _ := CompareValues(value1, value2)
+		return cmp
+	})
+	if !acc.isContinuous {
+		idx := int(math.Ceil(acc.fraction*float64(len(acc.values)))) - 1
+		return acc.values[max(idx, 0)]
+	}
+	position := acc.fraction * float64(len(acc.values)-1)
+	lower, upper := int(math.Floor(position)), int(math.Ceil(position))
+	if lower == upper {
+		return acc.values[lower]
+	}
+	lowerValue, upperValue := acc.values[lower].AsFloat(), acc.values[upper].AsFloat()
+	return FloatValue(lowerValue + (upperValue-lowerValue)*(position-float64(lower)))
+}
+
+func NewModeAccumulator(params []Value) (Accumulator, error) {
+	return &ModeAccumulator{counts: map[string]int64{}}, nil
+}
+
+// Most frequent value, ties go to the value seen first
+type ModeAccumulator struct {
+	counts map[string]int64
+	values []Value // Distinct values in first-seen order
+	keys   []string
+}
+
+func (acc *ModeAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	key := GroupKey([]Value{value})
+	if _, seen := acc.counts[key]; !seen {
+		acc.values = append(acc.values, value)
+		acc.keys = append(acc.keys, key)
+	}
+	acc.counts[key]++
+	return nil
+}
+
+func (acc *ModeAccumulator) Result() Value {
+	mode := NullValue()
+	modeCount := int64(0)
+	for i, key := range acc.keys {
+		if acc.counts[key] > modeCount {
+			mode = acc.values[i]
+			modeCount = acc.counts[key]
+		}
+	}
+	return mode
+}
@@ -0,0 +1,177 @@
+package pkg
+
+import "testing"
+
+// Feed values into new accumulator and return its result
+func accumulate(t *testing.T, acc Accumulator, values ...Value) Value {
+	t.Helper()
+	for _, value := range values {
+		if err := acc.Add(value); err != nil {
+			t.Fatalf("Add(%v): %v", value.String(), err)
+		}
+	}
+	return acc.Result()
+}
+
+func TestVarianceAccumulator(t *testing.T) {
+	// Integers beyond float precision keep exact variance
+	acc := &VarianceAccumulator{name: "VARIANCE", isSample: true}
+	large := int64(100_000_000_000_000_000)
+	if got := accumulate(t, acc, IntValue(large+1), IntValue(large+2), NullValue(), IntValue(large+3)); got.String() != "1" || acc.isFloat {
+		t.Errorf("exact VARIANCE = %v, isFloat %v, want 1, false", got.String(), acc.isFloat)
+	}
+
+	// First float switches to Welford's algorithm, seeded with exact mean and sum of squares so far
+	acc = &VarianceAccumulator{name: "VARIANCE", isSample: true}
+	base := int64(1_000_000_000_000_000)
+	if got := accumulate(t, acc, IntValue(base+1), ParseValue("1000000000000002.0"), IntValue(base+3)); got.String() != "1" || acc.isFloat {
+		t.Errorf("exact VARIANCE = %v, isFloat %v, want 1, false", got.String(), acc.isFloat)
+	}
+	if got := accumulate(t, acc, FloatValue(float64(base+4))); got.String() != "1.6666666666666667" || !acc.isFloat {
+		t.Errorf("VARIANCE after float = %v, isFloat %v, want 1.6666666666666667, true", got.String(), acc.isFloat)
+	}
+	if got := accumulate(t, acc, IntValue(base+5)); got.String() != "2.5" {
+		t.Errorf("VARIANCE after int following float = %v, want 2.5", got.String())
+	}
+
+	tests := []struct {
+		acc    Accumulator
+		values []Value
+		want   string
+	}{
+		{&VarianceAccumulator{name: "VAR_POP"}, []Value{IntValue(2), IntValue(4), IntValue(4), IntValue(4), IntValue(5), IntValue(5), IntValue(7), IntValue(9)}, "4"},
+		{&VarianceAccumulator{name: "STDDEV_POP", isStddev: true}, []Value{FloatValue(2), FloatValue(4), FloatValue(4), FloatValue(4), FloatValue(5), FloatValue(5), FloatValue(7), FloatValue(9)}, "2"},
+		// Sample variance needs two values, population variance one
+		{&VarianceAccumulator{name: "VARIANCE", isSample: true}, []Value{IntValue(3)}, ""},
+		{&VarianceAccumulator{name: "VAR_POP"}, []Value{IntValue(3)}, "0"},
+		{&VarianceAccumulator{name: "VAR_POP"}, []Value{NullValue()}, ""},
+	}
+	for _, test := range tests {
+		if got := accumulate(t, test.acc, test.values...); got.String() != test.want {
+			t.Errorf("%v of %d values = %q, want %q", test.acc.(*VarianceAccumulator).name, len(test.values), got.String(), test.want)
+		}
+	}
+}
+
+func TestPercentileAccumulator(t *testing.T) {
+	values := []Value{IntValue(40), IntValue(5), IntValue(20), NullValue(), IntValue(10), IntValue(30)}
+	tests := []struct {
+		fraction     float64
+		isContinuous bool
+		want         string
+	}{
+		// Position fraction * (n - 1) between 10 and 20
+		{0.25, true, "10"},
+		{0.3, true, "12"},
+		{0.5, true, "20"},
+		{0.9, true, "36"},
+		{1, true, "40"},
+		// First value whose position reaches fraction * n
+		{0, false, "5"},
+		{0.3, false, "10"},
+		{0.5, false, "20"},
+		{0.61, false, "30"},
+	}
+	for _, test := range tests {
+		acc := &PercentileAccumulator{name: "PERCENTILE", fraction: test.fraction, isContinuous: test.isContinuous}
+		if got := accumulate(t, acc, values...); got.String() != test.want {
+			t.Errorf("percentile %v (continuous %v) = %q, want %q", test.fraction, test.isContinuous, got.String(), test.want)
+		}
+	}
+	if _, err := NewPercentileAccumulator("PERCENTILE_CONT", FloatValue(1.5), true); err == nil {
+		t.Error("fraction 1.5 want error")
+	}
+}
+
+func TestExecuteStatAggregates(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT region, VARIANCE(amount), VAR_POP(amount), STDDEV_POP(amount), AVG(score) FROM sales GROUP BY region",
+			[][]string{
+				{"region", "VARIANCE(amount)", "VAR_POP(amount)", "STDDEV_POP(amount)", "AVG(score)"},
+				{"east", "33.333333333333336", "22.222222222222225", "4.714045207910317", "1.75"},
+				{"west", "158.33333333333334", "105.55555555555556", "10.274023338281628", "1.5"},
+				{"north", "", "0", "0", "4"},
+			},
+		},
+		{
+			"SELECT MEDIAN(amount), PERCENTILE_CONT(amount, 0.25), PERCENTILE_DISC(amount, 0.25), MEDIAN(score), MODE(rep) FROM sales",
+			[][]string{
+				{"MEDIAN(amount)", "PERCENTILE_CONT(amount, 0.25)", "PERCENTILE_DISC(amount, 0.25)", "MEDIAN(score)", "MODE(rep)"},
+				{"20", "12.5", "10", "1.75", "Ann"},
+			},
+		},
+		{
+			"SELECT region, STRING_AGG(rep, '/' ORDER BY amount DESC, rep), STRING_AGG(DISTINCT rep, ',' ORDER BY rep DESC), GROUP_CONCAT(rep) FROM sales GROUP BY region",
+			[][]string{
+				{"region", "STRING_AGG(rep, '/' ORDER BY amount DESC, rep)", "STRING_AGG(DISTINCT rep, ',' ORDER BY rep DESC)", "GROUP_CONCAT(rep)"},
+				{"east", "Ann/Ben/Ann", "Ben,Ann", "Ann,Ben,Ann"},
+				{"west", "Cid/Dee/Cid", "Dee,Cid", "Cid,Dee,Cid"},
+				{"north", "Eve", "Eve", "Eve"},
+			},
+		},
+		{
+			"SELECT BOOL_AND(amount > 4), BOOL_OR(amount > 35), BOOL_AND(score > 1), FIRST(rep ORDER BY amount DESC), LAST(rep ORDER BY id), MIN(rep), MAX(score) FROM sales",
+			[][]string{
+				{"BOOL_AND(amount > 4)", "BOOL_OR(amount > 35)", "BOOL_AND(score > 1)", "FIRST(rep ORDER BY amount DESC)", "LAST(rep ORDER BY id)", "MIN(rep)", "MAX(score)"},
+				{"true", "true", "false", "Eve", "Eve", "Ann", "4"},
+			},
+		},
+	})
+}
+
+func TestExecuteWholeTableAggregates(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			// COUNT(*) counts rows, COUNT(column) skips NULLs
+			"SELECT COUNT(*), COUNT(score), SUM(amount), AVG(amount), MIN(amount), MAX(rep) FROM sales",
+			[][]string{{"COUNT(*)", "COUNT(score)", "SUM(amount)", "AVG(amount)", "MIN(amount)", "MAX(rep)"}, {"7", "6", "140", "20", "5", "Eve"}},
+		},
+		{
+			// Whole table is one group even when no row is left
+			"SELECT COUNT(*), SUM(amount), MAX(rep) FROM sales WHERE id > 100",
+			[][]string{{"COUNT(*)", "SUM(amount)", "MAX(rep)"}, {"0", "", ""}},
+		},
+		{
+			"SELECT COUNT(*) FROM sales WHERE score IS NULL",
+			[][]string{{"COUNT(*)"}, {"1"}},
+		},
+	})
+
+	ql := CSVQL{Sql: "SELECT rep, COUNT(*) FROM sales", DatabasePath: "testdata", Variables: map[string]string{}}
+	ql.Execute()
+	want := `column "rep" must appear in GROUP BY or be used in an aggregate function`
+	if err, ok := ql.Error.(*SyntaxError); !ok || err.Message != want || err.Pos != 7 {
+		t.Errorf("mixing aggregate and column without GROUP BY: got %v, want %q at 7", ql.Error, want)
+	}
+}
+
+func TestExecuteAggregateExpressions(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT SUM(amount * 2) / COUNT(*), MAX(LENGTH(rep)), SUM(CASE WHEN region = 'east' THEN amount ELSE 0 END) AS east FROM sales",
+			[][]string{{"SUM(amount * 2) / COUNT(*)", "MAX(LENGTH(rep))", "east"}, {"40", "3", "50"}},
+		},
+		{
+			"SELECT region, SUM(amount) / COUNT(*) AS mean, MAX(amount) - MIN(amount) FROM sales GROUP BY region HAVING MAX(amount) - MIN(amount) > 0",
+			[][]string{{"region", "mean", "MAX(amount) - MIN(amount)"}, {"east", "16.66666666666667", "10"}, {"west", "16.66666666666667", "25"}},
+		},
+		{
+			"SELECT COUNT(rep || ''), COUNT(DISTINCT UPPER(rep)) FROM sales",
+			[][]string{{"COUNT(rep || '')", "COUNT(DISTINCT UPPER(rep))"}, {"7", "5"}},
+		},
+		{
+			// DISTINCT keys values by text like GROUP BY, so 1 and 1.0 are two values
+			"SELECT COUNT(DISTINCT cust_id), SUM(DISTINCT cust_id), COUNT(cust_id) FROM orders",
+			[][]string{{"COUNT(DISTINCT cust_id)", "SUM(DISTINCT cust_id)", "COUNT(cust_id)"}, {"4", "8.0", "5"}},
+		},
+		{
+			"SELECT cust_id, COUNT(*) FROM orders GROUP BY cust_id",
+			[][]string{{"cust_id", "COUNT(*)"}, {"1", "2"}, {"2", "1"}, {"4", "1"}, {"1.0", "1"}},
+		},
+	})
+
+	if err := syntaxError(t, "SELECT SUM(COUNT(*)) FROM sales"); err.Message != "aggregate function calls can not be nested" {
+		t.Errorf("nested aggregate: got %v", err)
+	}
+}
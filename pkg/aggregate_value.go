@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// STRING_AGG(value, separator) and GROUP_CONCAT(value [, separator]), separator defaults to ","
+func NewStringAggAccumulator(params []Value) (Accumulator, error) {
+	separator := ","
+	if len(params) > 0 {
+		if params[0].IsNull() {
+			return nil, fmt.Errorf("separator can not be NULL")
+		}
+		separator = params[0].String()
+	}
+	return &StringAggAccumulator{separator: separator}, nil
+}
+
+type StringAggAccumulator struct {
+	separator string
+	parts     []string
+}
+
+func (acc *StringAggAccumulator) Add(value Value) error {
+	if !value.IsNull() {
+		acc.parts = append(acc.parts, value.String())
+	}
+	return nil
+}
+
+func (acc *StringAggAccumulator) Result() Value {
+	if len(acc.parts) == 0 {
+		return NullValue()
+	}
+	return StringValue(strings.Join(acc.parts, acc.separator))
+}
+
+func NewBoolAndAccumulator(params []Value) (Accumulator, error) {
+	return &BoolAccumulator{name: "BOOL_AND", result: NullValue(), isAnd: true}, nil
+}
+
+func NewBoolOrAccumulator(params []Value) (Accumulator, error) {
+	return &BoolAccumulator{name: "BOOL_OR", result: NullValue()}, nil
+}
+
+// BOOL_AND is true when every value is true, BOOL_OR when any value is true
+type BoolAccumulator struct {
+	name   string
+	result Value
+	isAnd  bool
+}
+
+func (acc *BoolAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	if value.Kind != KindBool {
+		return fmt.Errorf("%v expects boolean, got %v '%v'", acc.name, value.Kind, value.String())
+	}
+	if acc.result.IsNull() {
+		acc.result = value
+	} else if acc.isAnd {
+		acc.result = BoolValue(acc.result.Bool && value.Bool)
+	} else {
+		acc.result = BoolValue(acc.result.Bool || value.Bool)
+	}
+	return nil
+}
+
+func (acc *BoolAccumulator) Result() Value {
+	return acc.result
+}
+
+func NewFirstAccumulator(params []Value) (Accumulator, error) {
+	return &FirstLastAccumulator{value: NullValue()}, nil
+}
+
+func NewLastAccumulator(params []Value) (Accumulator, error) {
+	return &FirstLastAccumulator{value: NullValue(), isLast: true}, nil
+}
+
+// First or last non-NULL value in input order, or in ORDER BY order within the call
+type FirstLastAccumulator struct {
+	value  Value
+	isLast bool
+}
+
+func (acc *FirstLastAccumulator) Add(value Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	if acc.isLast || acc.value.IsNull() {
+		acc.value = value
+	}
+	return nil
+}
+
+func (acc *FirstLastAccumulator) Result() Value {
+	return acc.value
+}
@@ -52,8 +52,17 @@ func NewQuery(sql string, databasePath string) CSVQL {
 }
 
 func (ql *CSVQL) Execute() {
+	ql.Error = nil
+	ql.Result = [][]string{}
+
 	ql.Tokenizer()
+	if ql.Error != nil {
+		return
+	}
 	ql.BuildAST()
+	if ql.Error != nil {
+		return
+	}
 	ql.ExecuteAST()
 }
 func (ql *CSVQL) ReadVariable() {
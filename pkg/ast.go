@@ -1,11 +1,10 @@
 package pkg
 
 import (
-	"errors"
 	"fmt"
+	"math"
 	"slices"
-
-	"github.com/kr/pretty"
+	"strings"
 )
 
 // Struct of common expression
@@ -15,334 +14,900 @@ type BinaryExpr struct {
 	Right interface{}
 }
 
+// Struct of prefix expression, e.g. NOT expr
+type UnaryExpr struct {
+	Op   Token
+	Expr interface{}
+}
+
 // Struct of BETWEEN expression
 type BetweenExpr struct {
 	Expr  interface{}
 	Lower interface{}
 	Upper interface{}
+	Not   bool
 }
 
 // Struct of IN expression
 type InExpr struct {
 	Expr      interface{}
 	Collector interface{}
+	Not       bool
+}
+
+// Struct of IS [NOT] NULL expression
+type IsNullExpr struct {
+	Expr interface{}
+	Not  bool
+}
+
+// Struct of CASE expression. Operand is nil for searched CASE WHEN condition THEN ...
+type CaseExpr struct {
+	Operand interface{}
+	Whens   []WhenClause
+	Else    interface{}
+}
+
+type WhenClause struct {
+	Condition interface{}
+	Result    interface{}
+}
+
+// Flip negatable expression, e.g. IN into NOT IN
+func Negate(expr interface{}) interface{} {
+	switch typed := expr.(type) {
+	case BetweenExpr:
+		typed.Not = !typed.Not
+		return typed
+	case InExpr:
+		typed.Not = !typed.Not
+		return typed
+	case LikeExpr:
+		typed.Not = !typed.Not
+		return typed
+	default:
+		return UnaryExpr{
+			Op:   Token{Type: TokenNot, Value: "NOT"},
+			Expr: expr,
+		}
+	}
 }
 
 type OrderBySingle struct {
 	Field     string
+	Expr      interface{}
 	Direction TokenType
+	Nulls     TokenType // TokenNullsFirst or TokenNullsLast, otherwise NULL sorts as smallest value
+	Column    int       // 1-based position of output column used as key, 0 when key is computed from source row
 }
 
 type Column struct {
 	Type   TokenType
 	Value  interface{}
+	Expr   interface{}
 	Pos    int
 	EndPos int
 	Alias  string
 }
 
-type AST struct {
-	Columns []Column
-	From    interface{}
-	Where   Expr
-	OrderBy []OrderBySingle
-	Limit   int
-	GroupBy []Token
+// Token spanning the column, used to report errors
+func (col Column) Token() Token {
+	return Token{
+		Type:   col.Type,
+		Value:  col.Value,
+		Pos:    col.Pos,
+		EndPos: col.EndPos,
+	}
 }
 
-// Check whether token existed
-func Expect(token Token, expectedType TokenType) (bool, error) {
-	if token.Type != expectedType {
-		return false, errors.New(fmt.Sprintf("Syntax error: No SELECT statement found"))
+// Direct sub-expressions of expression
+func ExprChildren(expr interface{}) []interface{} {
+	switch typed := expr.(type) {
+	case BinaryExpr:
+		return []interface{}{typed.Left, typed.Right}
+	case UnaryExpr:
+		return []interface{}{typed.Expr}
+	case BetweenExpr:
+		return []interface{}{typed.Expr, typed.Lower, typed.Upper}
+	case InExpr:
+		collector, _ := typed.Collector.([]interface{})
+		return append([]interface{}{typed.Expr}, collector...)
+	case IsNullExpr:
+		return []interface{}{typed.Expr}
+	case LikeExpr:
+		return []interface{}{typed.Expr, typed.Pattern, typed.Escape}
+	case FunctionExpr:
+		children := []interface{}{}
+		for _, arg := range typed.Args {
+			children = append(children, arg)
+		}
+		return children
+	case WindowExpr:
+		children := []interface{}{}
+		if typed.Aggregate != nil {
+			children = append(children, ExprChildren(*typed.Aggregate)...)
+		}
+		for _, arg := range typed.Args {
+			children = append(children, arg)
+		}
+		for _, expr := range typed.PartitionBy {
+			children = append(children, expr)
+		}
+		for _, condition := range typed.OrderBy {
+			children = append(children, condition.Expr)
+		}
+		return children
+	case AggregateExpr:
+		children := []interface{}{typed.Arg}
+		for _, param := range typed.Params {
+			children = append(children, param)
+		}
+		for _, condition := range typed.OrderBy {
+			children = append(children, condition.Expr)
+		}
+		return children
+	case CaseExpr:
+		children := []interface{}{typed.Operand}
+		for _, when := range typed.Whens {
+			children = append(children, when.Condition, when.Result)
+		}
+		return append(children, typed.Else)
+	default:
+		return nil
 	}
-	return true, nil
 }
 
-// Parse aggregate function
-func ParseAggregateFn(tokens []Token, pointer int) (Token, int) {
-	if tokens[pointer+1].Type != TokenLParen || tokens[pointer+3].Type != TokenRParen {
-		panic("Syntax error")
+// Names of columns referenced by expression outside of aggregate function calls
+func ExprColumns(expr interface{}) []string {
+	if token, isToken := expr.(Token); isToken {
+		if token.Type == TokenIdent {
+			return []string{Stringify(token.Value)}
+		}
+		return nil
+	}
+	if _, isAggregate := expr.(AggregateExpr); isAggregate {
+		return nil
+	}
+	columns := []string{}
+	for _, child := range ExprChildren(expr) {
+		if child != nil {
+			columns = append(columns, ExprColumns(child)...)
+		}
+	}
+	return columns
+}
+
+// Names of all columns referenced by expression, including arguments of aggregate and window functions
+func SourceColumns(expr interface{}) []string {
+	if token, isToken := expr.(Token); isToken {
+		if token.Type == TokenIdent {
+			return []string{Stringify(token.Value)}
+		}
+		return nil
 	}
-	return tokens[pointer+2], pointer + 3
+	columns := []string{}
+	for _, child := range ExprChildren(expr) {
+		if child != nil {
+			columns = append(columns, SourceColumns(child)...)
+		}
+	}
+	return columns
 }
 
-func ParseAlias(tokens []Token, pointer int) (Token, int) {
-	if tokens[pointer+1].Type != TokenIdent {
-		panic("Syntax error")
+// Binding power of infix operators, higher binds tighter
+var infixBindingPower = map[TokenType]int{
+	TokenOr:           100,
+	TokenAnd:          200,
+	TokenGreater:      500,
+	TokenGreaterEqual: 500,
+	TokenLess:         500,
+	TokenLessEqual:    500,
+	TokenEqual:        500,
+	TokenNotEqual:     500,
+	TokenConcat:       550,
+	TokenPlus:         600,
+	TokenMinus:        600,
+	TokenStar:         700,
+	TokenSlash:        700,
+	TokenPercent:      700,
+}
+
+// Binding power of unary minus and plus
+const unaryBindingPower = 800
+
+// Register operator precedence of scalar expression used by SELECT, WHERE and ORDER BY
+func RegisterExpressionOperators(p *Parser) {
+	(*p).RegisterPrefix(TokenNumber, 0)
+	(*p).RegisterPrefix(TokenString, 0)
+	(*p).RegisterIdentifierPrefix(TokenIdent, 0)
+	(*p).RegisterPrefix(TokenBoolean, 0)
+	(*p).RegisterPrefix(TokenNull, 0)
+	(*p).RegisterGroupPrefix(TokenLParen, 0)
+	(*p).RegisterCasePrefix(TokenCase, 0)
+	for _, tokenType := range []TokenType{TokenSum, TokenCount, TokenAverage, TokenMax, TokenMin} {
+		(*p).RegisterAggregatePrefix(tokenType, 0)
 	}
-	return tokens[pointer+1], pointer + 1
+	for tokenType, lbp := range infixBindingPower {
+		(*p).RegisterInfix(tokenType, lbp)
+	}
+	(*p).RegisterNotOperator(TokenNot, 300, 400)
+	(*p).RegisterBetweenInfix(TokenBetween, 400)
+	(*p).RegisterInInfix(TokenIn, 400)
+	(*p).RegisterIsNullInfix(TokenIs, 400)
+	(*p).RegisterLikeInfix(TokenLike, 400)
+	(*p).RegisterLikeInfix(TokenILike, 400)
+	(*p).RegisterLikeInfix(TokenRegexp, 400)
+	(*p).RegisterUnaryPrefix(TokenMinus, unaryBindingPower)
+	(*p).RegisterUnaryPrefix(TokenPlus, unaryBindingPower)
 }
 
-func ParseColumn(tokens []Token, pointer int) (Column, int) {
-	token := tokens[pointer]
-	pointer++
-	nextToken := tokens[pointer]
-	isDot := IsDot(Stringify(nextToken.Value))
+// Parse one expression starting at pointer, return it with index of first token after it
+func ParseExpressionAt(tokens []Token, pointer int) (Expr, int, error) {
+	p := NewParser(tokens[pointer:])
+	RegisterExpressionOperators(p)
+	expr, err := p.ParseExpression(0)
+	if err != nil {
+		return nil, pointer, err
+	}
+	return expr, pointer + p.pointer, nil
+}
 
-	if !isDot {
-		pointer--
-		return Column{
-			Type:   token.Type,
-			Value:  token.Value,
-			Pos:    token.Pos,
-			EndPos: token.EndPos,
-			Alias:  "",
-		}, pointer
+// Render expression back to SQL text, used as name of computed column
+func ExprString(expr interface{}) string {
+	switch typed := expr.(type) {
+	case Token:
+		switch typed.Type {
+		case TokenString:
+			return fmt.Sprintf("'%v'", strings.ReplaceAll(Stringify(typed.Value), "'", "''"))
+		case TokenNull:
+			return "NULL"
+		case TokenNumber:
+			// Number is named as written, e.g. 1.5e3 rather than 1500
+			if len(typed.Text) > 0 {
+				return typed.Text
+			}
+			return Stringify(typed.Value)
+		default:
+			return Stringify(typed.Value)
+		}
+	case BinaryExpr:
+		lbp := infixBindingPower[typed.Op.Type]
+		return fmt.Sprintf("%v %v %v", ExprOperandString(typed.Left, lbp, false), typed.Op.Value, ExprOperandString(typed.Right, lbp, true))
+	case UnaryExpr:
+		if typed.Op.Type == TokenNot {
+			return fmt.Sprintf("NOT %v", ExprOperandString(typed.Expr, 300, false))
+		}
+		return fmt.Sprintf("%v%v", typed.Op.Value, ExprOperandString(typed.Expr, unaryBindingPower, false))
+	case FunctionExpr:
+		switch typed.Function.Name {
+		case "CAST":
+			typeName := Stringify(typed.Args[1].(Token).Value)
+			if len(typed.Args) > 2 {
+				modifiers := []string{}
+				for _, modifier := range typed.Args[2:] {
+					modifiers = append(modifiers, ExprString(modifier))
+				}
+				typeName = fmt.Sprintf("%v(%v)", typeName, strings.Join(modifiers, ", "))
+			}
+			return fmt.Sprintf("CAST(%v AS %v)", ExprString(typed.Args[0]), typeName)
+		case "EXTRACT":
+			return fmt.Sprintf("EXTRACT(%v FROM %v)", strings.ToUpper(Stringify(typed.Args[0].(Token).Value)), ExprString(typed.Args[1]))
+		}
+		args := []string{}
+		for _, arg := range typed.Args {
+			args = append(args, ExprString(arg))
+		}
+		return fmt.Sprintf("%v(%v)", strings.ToUpper(Stringify(typed.Name.Value)), strings.Join(args, ", "))
+	case AggregateExpr:
+		return AggregateString(typed)
+	case WindowExpr:
+		return WindowString(typed)
+	case GroupingExpr:
+		return GroupingString(typed)
+	case CaseExpr:
+		parts := []string{"CASE"}
+		if typed.Operand != nil {
+			parts = append(parts, ExprString(typed.Operand))
+		}
+		for _, when := range typed.Whens {
+			parts = append(parts, "WHEN", ExprString(when.Condition), "THEN", ExprString(when.Result))
+		}
+		if typed.Else != nil {
+			parts = append(parts, "ELSE", ExprString(typed.Else))
+		}
+		return strings.Join(append(parts, "END"), " ")
+	case IsNullExpr:
+		if typed.Not {
+			return fmt.Sprintf("%v IS NOT NULL", ExprOperandString(typed.Expr, 400, false))
+		}
+		return fmt.Sprintf("%v IS NULL", ExprOperandString(typed.Expr, 400, false))
+	case BetweenExpr:
+		return fmt.Sprintf("%v %vBETWEEN %v AND %v", ExprOperandString(typed.Expr, 400, false), NotPrefix(typed.Not), ExprOperandString(typed.Lower, 400, true), ExprOperandString(typed.Upper, 400, true))
+	case InExpr:
+		items := []string{}
+		collector, _ := typed.Collector.([]interface{})
+		for _, item := range collector {
+			items = append(items, ExprString(item))
+		}
+		return fmt.Sprintf("%v %vIN (%v)", ExprOperandString(typed.Expr, 400, false), NotPrefix(typed.Not), strings.Join(items, ", "))
+	case LikeExpr:
+		result := fmt.Sprintf("%v %v%v %v", ExprOperandString(typed.Expr, 400, false), NotPrefix(typed.Not), typed.Op.Value, ExprOperandString(typed.Pattern, 400, true))
+		if typed.Escape != nil {
+			result = fmt.Sprintf("%v ESCAPE %v", result, ExprString(typed.Escape))
+		}
+		return result
+	default:
+		return Stringify(expr)
 	}
+}
 
-	pointer++
-	nextToken = tokens[pointer]
+// Render operand, wrap in parentheses when it binds looser than its parent operator
+func ExprOperandString(expr interface{}, parentBp int, isRight bool) string {
+	bp := ExprBindingPower(expr)
+	if bp < parentBp || (isRight && bp == parentBp) {
+		return fmt.Sprintf("(%v)", ExprString(expr))
+	}
+	return ExprString(expr)
+}
 
-	if nextToken.Type != TokenIdent {
-		panic("Syntax error")
+// Binding power of expression's top operator, atoms bind tightest
+func ExprBindingPower(expr interface{}) int {
+	switch typed := expr.(type) {
+	case BinaryExpr:
+		return infixBindingPower[typed.Op.Type]
+	case UnaryExpr:
+		if typed.Op.Type == TokenNot {
+			return 300
+		}
+		return unaryBindingPower
+	case IsNullExpr, BetweenExpr, InExpr, LikeExpr:
+		return 400
+	default:
+		return math.MaxInt
+	}
+}
+
+func NotPrefix(isNot bool) string {
+	if isNot {
+		return "NOT "
+	}
+	return ""
+}
+
+type AST struct {
+	Columns  []Column
+	From     interface{}
+	Where    Expr
+	OrderBy  []OrderBySingle
+	Limit    int // -1 when rows are not limited
+	Offset   int
+	GroupBy  []Expr
+	Having   Expr
+	Distinct bool
+
+	GroupingSets [][]Expr // Sets of ROLLUP, CUBE and GROUPING SETS, nil for plain GROUP BY
+}
+
+// Check whether token existed
+func Expect(token Token, expectedType TokenType) (bool, error) {
+	if token.Type != expectedType {
+		return false, UnexpectedTokenError(token, expectedType)
 	}
+	return true, nil
+}
 
-	return Column{
-		Type:   token.Type,
-		Value:  []string{Stringify(token.Value), Stringify(nextToken.Value)},
-		Pos:    token.Pos,
-		EndPos: token.EndPos,
-		Alias:  "",
-	}, pointer
+func ParseAlias(tokens []Token, pointer int) (Token, int, error) {
+	if _, err := Expect(tokens[pointer+1], TokenIdent); err != nil {
+		return Token{}, pointer, err
+	}
+	return tokens[pointer+1], pointer + 1, nil
 }
 
 // Get columns of SELECT statement
-func ParseColumns(tokens []Token, pointer int) ([]Column, int) {
+func ParseColumns(tokens []Token, pointer int) ([]Column, int, error) {
 	columns := []Column{}
 
-	for pointer < len(tokens) {
+	if tokens[pointer].Type == TokenStar {
 		token := tokens[pointer]
-		fmt.Println("pointer", pointer, token)
-		isNext, _ := Expect(token, TokenIdent)
-		isFromStmt, _ := Expect(token, TokenFrom)
-		isComma := IsComma(Stringify(token.Value))
-		isStar := IsStar(Stringify(token.Value))
-		isAggregateFn := IsAggregateFn(Column{
+		columns = append(columns, Column{
 			Type:   token.Type,
 			Value:  token.Value,
 			Pos:    token.Pos,
 			EndPos: token.EndPos,
-			Alias:  "",
 		})
-		isAlias, _ := Expect(token, TokenAs)
+		return columns, pointer, nil
+	}
 
-		if isStar {
-			columns = append(columns, Column{
-				Type:   token.Type,
-				Value:  token.Value,
-				Pos:    token.Pos,
-				EndPos: token.EndPos,
-				Alias:  "123",
-			})
-			pointer++
-			break
+	for pointer < len(tokens) {
+		token := tokens[pointer]
+		// Column, qualified column, e.g. users.id, or computed column, e.g. salary * 12 or SUM(price * qty) / COUNT(*)
+		expr, endIdx, err := ParseExpressionAt(tokens, pointer)
+		if err != nil {
+			return nil, pointer, err
 		}
+		columns = append(columns, Column{
+			Type:   token.Type,
+			Value:  ExprString(expr),
+			Expr:   expr,
+			Pos:    token.Pos,
+			EndPos: tokens[endIdx-1].EndPos,
+		})
+		pointer = endIdx
 
-		if isFromStmt {
-			break
+		if tokens[pointer].Type == TokenAs {
+			aliasToken, endIdx, err := ParseAlias(tokens, pointer)
+			if err != nil {
+				return nil, pointer, err
+			}
+			columns[len(columns)-1].Alias = Stringify(aliasToken.Value)
+			pointer = endIdx + 1
 		}
 
-		if isNext && !isComma {
-			// columns = append(columns, Column{
-			// 	Type:   token.Type,
-			// 	Value:  token.Value,
-			// 	Pos:    token.Pos,
-			// 	EndPos: token.EndPos,
-			// 	Alias:  "",
-			// })
-			column, endIdx := ParseColumn(tokens, pointer)
-			columns = append(columns, column)
-			pointer = endIdx
-		}
-
-		if isAggregateFn {
-			fieldToken, endIdx := ParseAggregateFn(tokens, pointer)
-			columns = append(columns, Column{
-				Type:  token.Type,
-				Value: fieldToken.Value,
-			})
-			pointer = endIdx
-		}
-
-		if isAlias {
-			aliasToken, endIdx := ParseAlias(tokens, pointer)
-			lastColumn := columns[len(columns)-1]
-			columns[len(columns)-1] = Column{
-				Type:   lastColumn.Type,
-				Value:  lastColumn.Value,
-				Pos:    lastColumn.Pos,
-				EndPos: lastColumn.EndPos,
-				Alias:  Stringify(aliasToken.Value),
-			}
-			pointer = endIdx
-		}
-
-		// if isSum {
-		// 	fieldToken, endIdx := ParseAggregateFn(tokens, pointer)
-		// 	columns = append(columns, Token{
-		// 		Type:  TokenSum,
-		// 		Value: fieldToken.Value,
-		// 	})
-		// 	pointer = endIdx
-		// }
-
-		// if isMax {
-		// 	fieldToken, endIdx := ParseSum(tokens, pointer)
-		// 	columns = append(columns, Token{
-		// 		Type:  TokenMax,
-		// 		Value: fieldToken.Value,
-		// 	})
-		// 	pointer = endIdx
-		// }
+		if tokens[pointer].Type != TokenComma {
+			break
+		}
 		pointer++
 	}
 	pointer--
-	return columns, pointer
+	return columns, pointer, nil
 }
 
 // Get table of FROM statement
 func CheckStopParseFrom(token Token) bool {
-	stopTokens := []TokenType{TokenWhere, TokenGroupBy, TokenOrderBy, TokenLimit, TokenEOF}
+	stopTokens := []TokenType{TokenWhere, TokenGroupBy, TokenHaving, TokenOrderBy, TokenLimit, TokenOffset, TokenFetch, TokenEOF}
 	return slices.Contains(stopTokens, token.Type)
 }
-func ParseFrom(tokens []Token, pointer int) (interface{}, int) {
-	// return tokens[pointer], pointer
+func ParseFrom(tokens []Token, pointer int) (interface{}, int, error) {
 	fromTokens := []Token{}
 	for pointer < len(tokens) {
 		token := tokens[pointer]
 		if CheckStopParseFrom(token) {
 			pointer--
-			fromTokens = append(fromTokens, tokens[len(tokens)-1])
+			fromTokens = append(fromTokens, Token{
+				Type:   TokenEOF,
+				Pos:    token.Pos,
+				EndPos: token.EndPos,
+			})
 			break
 		}
 		fromTokens = append(fromTokens, token)
 		pointer++
 	}
-	fmt.Println("fromTokens", fromTokens)
 
 	p := NewParserFrom(fromTokens)
-
-	(*p).RegisterPrefix(TokenNumber, 0)
-	(*p).RegisterPrefix(TokenString, 0)
-	(*p).RegisterPrefix(TokenIdent, 0)
-	(*p).RegisterInfix(TokenOr, 100)
-	(*p).RegisterInfix(TokenAnd, 200)
-	(*p).RegisterInfix(TokenEqual, 500)
-	fromExpression, _ := (*p).ParserFromExpression(0)
-	return fromExpression, pointer
+	fromExpression, err := (*p).ParserFromExpression()
+	if err != nil {
+		return nil, pointer, err
+	}
+	if p.current.Type != TokenEOF {
+		return nil, pointer, UnexpectedTokenError(p.current)
+	}
+	return fromExpression, pointer, nil
 }
 
 func CheckStopParseWhere(token Token) bool {
-	stopTokens := []TokenType{TokenOrderBy, TokenEOF}
+	stopTokens := []TokenType{TokenGroupBy, TokenHaving, TokenOrderBy, TokenLimit, TokenOffset, TokenFetch, TokenEOF}
 	return slices.Contains(stopTokens, token.Type)
 }
 
 // Get AST expression of WHERE statement
-func ParseWhere(tokens []Token, pointer int) (Expr, int) {
-	whereTokens := []Token{}
+func ParseWhere(tokens []Token, pointer int) (Expr, int, error) {
+	ast, pointer, err := ParseClauseExpr(tokens, pointer, CheckStopParseWhere)
+	if err != nil {
+		return nil, pointer, err
+	}
+	if aggregates := CollectAggregates(ast); len(aggregates) > 0 {
+		return nil, pointer, NewSyntaxError(aggregates[0].Name, "aggregate functions are not allowed in WHERE")
+	}
+	if windows := CollectWindows(ast); len(windows) > 0 {
+		return nil, pointer, NewSyntaxError(windows[0].Name, "window functions are not allowed in WHERE")
+	}
+	if groupings := CollectGroupings(ast); len(groupings) > 0 {
+		return nil, pointer, NewSyntaxError(groupings[0].Name, "GROUPING is not allowed in WHERE")
+	}
+	return ast, pointer, nil
+}
+
+// === Parse HAVING tokens ===
+func CheckStopParseHaving(token Token) bool {
+	stopTokens := []TokenType{TokenOrderBy, TokenLimit, TokenOffset, TokenFetch, TokenEOF}
+	return slices.Contains(stopTokens, token.Type)
+}
+
+// Get AST expression of HAVING statement
+func ParseHaving(tokens []Token, pointer int) (Expr, int, error) {
+	ast, pointer, err := ParseClauseExpr(tokens, pointer, CheckStopParseHaving)
+	if err != nil {
+		return nil, pointer, err
+	}
+	if windows := CollectWindows(ast); len(windows) > 0 {
+		return nil, pointer, NewSyntaxError(windows[0].Name, "window functions are not allowed in HAVING")
+	}
+	return ast, pointer, nil
+}
+
+// Parse expression of clause until stop token outside of parentheses, return pointer at stop token.
+// Stop tokens within parentheses belong to the expression, e.g. ORDER BY of STRING_AGG
+func ParseClauseExpr(tokens []Token, pointer int, isStop func(Token) bool) (Expr, int, error) {
+	clauseTokens := []Token{}
+	depth := 0
 	for pointer < len(tokens) {
 		token := tokens[pointer]
-		if CheckStopParseWhere(token) {
-			whereTokens = append(whereTokens, tokens[len(tokens)-1])
+		switch token.Type {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+		}
+		if token.Type == TokenEOF || (depth <= 0 && isStop(token)) {
+			clauseTokens = append(clauseTokens, Token{
+				Type:   TokenEOF,
+				Pos:    token.Pos,
+				EndPos: token.EndPos,
+			})
 			break
 		}
-		whereTokens = append(whereTokens, token)
+		clauseTokens = append(clauseTokens, token)
 		pointer++
 	}
 
-	p := NewParser(whereTokens)
+	p := NewParser(clauseTokens)
 
 	//Register operator precedence
-	(*p).RegisterPrefix(TokenNumber, 0)
-	(*p).RegisterPrefix(TokenString, 0)
-	(*p).RegisterPrefix(TokenIdent, 0)
-	(*p).RegisterInfix(TokenOr, 100)
-	(*p).RegisterInfix(TokenAnd, 200)
-	(*p).RegisterBetweenInfix(TokenBetween, 400)
-	(*p).RegisterInInfix(TokenIn, 400)
-	(*p).RegisterInfix(TokenGreater, 500)
-	(*p).RegisterInfix(TokenGreaterEqual, 500)
-	(*p).RegisterInfix(TokenLess, 500)
-	(*p).RegisterInfix(TokenLessEqual, 500)
-	(*p).RegisterInfix(TokenEqual, 500)
-	(*p).RegisterInfix(TokenNotEqual, 500)
+	RegisterExpressionOperators(p)
 
 	// Start parse expression from min_bp=0
-	ast := p.ParseExpression(0)
-
-	fmt.Println("whereTokens", whereTokens)
-	fmt.Printf("ast:%# v", pretty.Formatter(ast))
+	ast, err := p.ParseExpression(0)
+	if err != nil {
+		return nil, pointer, err
+	}
+	if p.current.Type != TokenEOF {
+		return nil, pointer, UnexpectedTokenError(p.current)
+	}
 
-	return ast, pointer
+	return ast, pointer, nil
 }
 
-func ParseGroupBy(tokens []Token, pointer int) ([]Token, int) {
-	groupBy := []Token{}
+// Parse GROUP BY list of expressions, ROLLUP(...), CUBE(...) and GROUPING SETS (...). Return all grouping
+// expressions and grouping sets, which are cross product of sets of each element. Plain list has no grouping sets
+func ParseGroupBy(tokens []Token, pointer int, columns []Column) ([]Expr, [][]Expr, int, error) {
+	groupingSets := [][]Expr{{}}
+	isGroupingSets := false
 	for pointer < len(tokens) {
-		token := tokens[pointer]
-		if IsEOF(token) {
-			break
+		var elementSets [][]Expr
+		switch tokens[pointer].Type {
+		case TokenRollup, TokenCube, TokenGroupingSets:
+			{
+				var err error
+				elementSets, pointer, err = ParseGroupingElement(tokens, pointer, columns)
+				if err != nil {
+					return nil, nil, pointer, err
+				}
+				isGroupingSets = true
+			}
+		default:
+			{
+				expr, endIdx, err := ParseGroupByExpr(tokens, pointer, columns)
+				if err != nil {
+					return nil, nil, pointer, err
+				}
+				elementSets = [][]Expr{{expr}}
+				pointer = endIdx
+			}
 		}
-		if token.Type != TokenIdent && !IsComma(Stringify(token.Value)) {
+		groupingSets = CrossGroupingSets(groupingSets, elementSets)
+
+		if tokens[pointer].Type != TokenComma {
 			break
 		}
-		if token.Type == TokenComma {
+		pointer++
+	}
+
+	groupBy := GroupingSetsExprs(groupingSets)
+	if !isGroupingSets {
+		return groupBy, nil, pointer, nil
+	}
+	return groupBy, groupingSets, pointer, nil
+}
+
+// Parse one GROUP BY expression. Position, e.g. GROUP BY 1, stands for expression of selected column,
+// alias of selected column is resolved after scan by ResolveGroupBy
+func ParseGroupByExpr(tokens []Token, pointer int, columns []Column) (Expr, int, error) {
+	expr, endIdx, err := ParseExpressionAt(tokens, pointer)
+	if err != nil {
+		return nil, pointer, err
+	}
+	isStar := columns[0].Type == TokenStar
+	if token, isToken := expr.(Token); isToken && token.Type == TokenNumber {
+		position, err := StringToInt(Stringify(token.Value))
+		if err != nil || position < 1 || position > len(columns) || isStar {
+			return nil, pointer, NewSyntaxError(token, fmt.Sprintf("GROUP BY position %v is not in select list", token.Value))
+		}
+		expr = columns[position-1].Expr
+	}
+	if err := ValidateGroupByExpr(expr); err != nil {
+		return nil, pointer, err
+	}
+	return expr, endIdx, nil
+}
+
+// Parse ROLLUP(a, b), CUBE(a, b) or GROUPING SETS ((a, b), a, ()) into its grouping sets
+func ParseGroupingElement(tokens []Token, pointer int, columns []Column) ([][]Expr, int, error) {
+	token := tokens[pointer]
+	switch token.Type {
+	case TokenRollup:
+		{
+			exprs, endIdx, err := ParseGroupingList(tokens, pointer+1, columns, false)
+			if err != nil {
+				return nil, pointer, err
+			}
+			return RollupSets(exprs), endIdx, nil
+		}
+	case TokenCube:
+		{
+			exprs, endIdx, err := ParseGroupingList(tokens, pointer+1, columns, false)
+			if err != nil {
+				return nil, pointer, err
+			}
+			if len(exprs) > maxCubeColumns {
+				return nil, pointer, NewSyntaxError(token, fmt.Sprintf("CUBE is limited to %d expressions", maxCubeColumns))
+			}
+			return CubeSets(exprs), endIdx, nil
+		}
+	default:
+		{
 			pointer++
-			continue
+			if _, err := Expect(tokens[pointer], TokenLParen); err != nil {
+				return nil, pointer, err
+			}
+			pointer++
+			sets := [][]Expr{}
+			for {
+				if tokens[pointer].Type == TokenLParen {
+					exprs, endIdx, err := ParseGroupingList(tokens, pointer, columns, true)
+					if err != nil {
+						return nil, pointer, err
+					}
+					sets = append(sets, exprs)
+					pointer = endIdx
+				} else {
+					expr, endIdx, err := ParseGroupByExpr(tokens, pointer, columns)
+					if err != nil {
+						return nil, pointer, err
+					}
+					sets = append(sets, []Expr{expr})
+					pointer = endIdx
+				}
+				if tokens[pointer].Type != TokenComma {
+					break
+				}
+				pointer++
+			}
+			if _, err := Expect(tokens[pointer], TokenRParen); err != nil {
+				return nil, pointer, UnexpectedTokenError(tokens[pointer], TokenComma, TokenRParen)
+			}
+			return sets, pointer + 1, nil
 		}
-		if token.Type == TokenIdent {
-			groupBy = append(groupBy, token)
+	}
+}
+
+// Parse parenthesized expression list of grouping element, return pointer after ")".
+// Empty list "()" is grand total set of GROUPING SETS
+func ParseGroupingList(tokens []Token, pointer int, columns []Column, allowEmpty bool) ([]Expr, int, error) {
+	if _, err := Expect(tokens[pointer], TokenLParen); err != nil {
+		return nil, pointer, err
+	}
+	pointer++
+	exprs := []Expr{}
+	if allowEmpty && tokens[pointer].Type == TokenRParen {
+		return exprs, pointer + 1, nil
+	}
+	for {
+		expr, endIdx, err := ParseGroupByExpr(tokens, pointer, columns)
+		if err != nil {
+			return nil, pointer, err
+		}
+		exprs = append(exprs, expr)
+		pointer = endIdx
+		if tokens[pointer].Type != TokenComma {
+			break
 		}
 		pointer++
 	}
-	return groupBy, pointer
+	if _, err := Expect(tokens[pointer], TokenRParen); err != nil {
+		return nil, pointer, UnexpectedTokenError(tokens[pointer], TokenComma, TokenRParen)
+	}
+	return exprs, pointer + 1, nil
 }
 
-// === Parse ORDER BY tokens ===
-func CheckStopParseOrderBy(token Token) bool {
-	stopTokens := []TokenType{TokenLimit, TokenEOF}
-	return slices.Contains(stopTokens, token.Type)
+// Check that ORDER BY of SELECT DISTINCT only uses selected columns, other values are ambiguous among merged rows
+func ValidateDistinctOrderBy(columns []Column, orderBy []OrderBySingle) error {
+	if columns[0].Type == TokenStar {
+		return nil
+	}
+	for _, condition := range orderBy {
+		if condition.Column == 0 {
+			return fmt.Errorf("for SELECT DISTINCT, ORDER BY expression %v must appear in select list", condition.Field)
+		}
+	}
+	return nil
 }
-func ParseOrderBy(tokens []Token, pointer int) ([]OrderBySingle, int) {
-	orderBy := []OrderBySingle{}
-	for pointer < len(tokens) {
-		token := tokens[pointer]
-		if CheckStopParseOrderBy(token) {
-			pointer--
-			return orderBy, pointer
+
+// Resolve ORDER BY keys naming output columns: position, e.g. ORDER BY 2, alias or name of selected column,
+// or expression equal to selected expression. Output names take precedence over source columns
+func ResolveOrderBy(orderBy []OrderBySingle, columns []Column) error {
+	isStar := columns[0].Type == TokenStar
+	for i, condition := range orderBy {
+		if token, isToken := condition.Expr.(Token); isToken && token.Type == TokenNumber {
+			position, err := StringToInt(Stringify(token.Value))
+			if err != nil || position < 1 || (!isStar && position > len(columns)) {
+				return NewSyntaxError(token, fmt.Sprintf("ORDER BY position %v is not in select list", token.Value))
+			}
+			orderBy[i].Column = position
+			continue
 		}
-		if token.Type == TokenComma {
-			pointer++
+		if isStar {
 			continue
 		}
-		if token.Type == TokenIdent {
-			orderBy = append(orderBy, OrderBySingle{
-				Field:     Stringify(token.Value),
-				Direction: TokenAsc,
-			})
+		if idx := OutputColumnPosition(condition.Expr, columns); idx >= 0 {
+			orderBy[i].Column = idx + 1
 		}
-		if token.Type == TokenAsc || token.Type == TokenDesc {
-			orderBy[len(orderBy)-1].Direction = token.Type
+	}
+	return nil
+}
+
+// Check ORDER BY positions of SELECT * against source header, which is only known after scan
+func ValidateStarOrderBy(orderBy []OrderBySingle, columns []Column, header []string) error {
+	if columns[0].Type != TokenStar {
+		return nil
+	}
+	for _, condition := range orderBy {
+		if condition.Column > len(header) {
+			token, _ := condition.Expr.(Token)
+			return NewSyntaxError(token, fmt.Sprintf("ORDER BY position %v is not in select list", token.Value))
+		}
+	}
+	return nil
+}
+
+// Index of selected column named by identifier, or whose expression equals the expression, -1 when none
+func OutputColumnPosition(expr interface{}, columns []Column) int {
+	if token, isToken := expr.(Token); isToken && token.Type == TokenIdent {
+		for i, col := range columns {
+			if ColumnName(col) == Stringify(token.Value) {
+				return i
+			}
+		}
+	}
+	for i, col := range columns {
+		if col.Expr != nil && ExprString(col.Expr) == ExprString(expr) {
+			return i
 		}
-		pointer++
 	}
-	return orderBy, pointer
+	return -1
 }
 
-// === Parse LIMIT tokens ===
-func ParseLimit(tokens []Token, pointer int) (int, int) {
-	limit := -1
+// SQL text of ORDER BY list, e.g. ORDER BY name, salary DESC
+func OrderByString(conditions []OrderBySingle) string {
+	parts := []string{}
+	for _, condition := range conditions {
+		part := condition.Field
+		if condition.Direction == TokenDesc {
+			part += " DESC"
+		}
+		if condition.Nulls != 0 {
+			part += " " + condition.Nulls.String()
+		}
+		parts = append(parts, part)
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// === Parse ORDER BY tokens ===
+func ParseOrderBy(tokens []Token, pointer int) ([]OrderBySingle, int, error) {
+	orderBy := []OrderBySingle{}
 	for pointer < len(tokens) {
+		expr, endIdx, err := ParseExpressionAt(tokens, pointer)
+		if err != nil {
+			return nil, pointer, err
+		}
+		orderBy = append(orderBy, OrderBySingle{
+			Field:     ExprString(expr),
+			Expr:      expr,
+			Direction: TokenAsc,
+		})
+		pointer = endIdx
+
 		token := tokens[pointer]
+		if token.Type == TokenAsc || token.Type == TokenDesc {
+			orderBy[len(orderBy)-1].Direction = token.Type
+			pointer++
+		}
+		token = tokens[pointer]
+		if token.Type == TokenNullsFirst || token.Type == TokenNullsLast {
+			orderBy[len(orderBy)-1].Nulls = token.Type
+			pointer++
+		}
 
-		if token.Type == TokenNumber {
-			limit, _ = StringToInt(Stringify(token.Value))
+		if tokens[pointer].Type != TokenComma {
 			break
 		}
+		pointer++
+	}
+	return orderBy, pointer, nil
+}
+
+// === Parse LIMIT, OFFSET and FETCH tokens ===
+// Accept LIMIT n [OFFSET m], LIMIT m, n and OFFSET m [ROWS] [FETCH FIRST [n] ROWS ONLY].
+// Return limit, -1 when rows are not limited, and offset
+func ParseLimitOffset(tokens []Token, pointer int) (int, int, int, error) {
+	limit, offset := -1, 0
+	if tokens[pointer].Type == TokenLimit {
+		count, endIdx, err := ParseRowCount(tokens, pointer+1, "LIMIT")
+		if err != nil {
+			return limit, offset, pointer, err
+		}
+		limit, pointer = count, endIdx
+
+		// LIMIT offset, count
+		if tokens[pointer].Type == TokenComma {
+			count, endIdx, err := ParseRowCount(tokens, pointer+1, "LIMIT")
+			if err != nil {
+				return limit, offset, pointer, err
+			}
+			return count, limit, endIdx, nil
+		}
+	}
 
+	if tokens[pointer].Type == TokenOffset {
+		count, endIdx, err := ParseRowCount(tokens, pointer+1, "OFFSET")
+		if err != nil {
+			return limit, offset, pointer, err
+		}
+		offset, pointer = count, endIdx
+		if IsRowsWord(tokens[pointer]) {
+			pointer++
+		}
+	}
+
+	if tokens[pointer].Type == TokenFetch {
+		if limit >= 0 {
+			return limit, offset, pointer, NewSyntaxError(tokens[pointer], "FETCH can not be used together with LIMIT")
+		}
+		pointer++
+		limit = 1
+		if tokens[pointer].Type == TokenNumber {
+			count, endIdx, err := ParseRowCount(tokens, pointer, "FETCH")
+			if err != nil {
+				return limit, offset, pointer, err
+			}
+			limit, pointer = count, endIdx
+		}
+		if _, err := Expect(tokens[pointer], TokenRowsOnly); err != nil {
+			return limit, offset, pointer, err
+		}
 		pointer++
 	}
-	return limit, pointer
+	return limit, offset, pointer, nil
+}
+
+// Parse non-negative integer number of rows of LIMIT, OFFSET or FETCH
+func ParseRowCount(tokens []Token, pointer int, clause string) (int, int, error) {
+	token := tokens[pointer]
+	if _, err := Expect(token, TokenNumber); err != nil {
+		return -1, pointer, err
+	}
+	count, err := StringToInt(Stringify(token.Value))
+	if err != nil || count < 0 {
+		return -1, pointer, NewSyntaxError(token, fmt.Sprintf("invalid %v %v", clause, token.Value))
+	}
+	pointer++
+	return count, pointer, nil
+}
+
+// ROW or ROWS after OFFSET count, outside of OVER clause they are identifiers
+func IsRowsWord(token Token) bool {
+	return token.Type == TokenIdent && slices.Contains([]string{"ROW", "ROWS"}, strings.ToUpper(Stringify(token.Value)))
 }
 
 // Build AST of whole query
@@ -350,72 +915,125 @@ func (ql *CSVQL) BuildAST() {
 	tokens := ql.Tokens
 	ast := AST{}
 	pointer := 0
+	ql.Ast = ast
+
 	//=== Expect SELECT ===
 	isNext, err := Expect(tokens[pointer], TokenSelect)
 	if !isNext {
-		ql.Ast = ast
-		ql.Error = err
+		ql.SetError(err)
 		return
 	}
 	pointer++
 
+	// === Expect DISTINCT ===
+	isNext, _ = Expect(tokens[pointer], TokenDistinct)
+	if isNext {
+		ast.Distinct = true
+		pointer++
+	}
+
 	// === Parse columns ===
-	columns, endIdx := ParseColumns(tokens, pointer)
+	columns, endIdx, err := ParseColumns(tokens, pointer)
+	if err != nil {
+		ql.SetError(err)
+		return
+	}
 	pointer = endIdx + 1
 	ast.Columns = columns
 
 	// === Expect FROM ===
 	isNext, err = Expect(tokens[pointer], TokenFrom)
 	if !isNext {
-		ql.Ast = ast
-		ql.Error = err
+		ql.SetError(err)
 		return
-	} else {
-		pointer++
+	}
+	pointer++
 
-		// === Parse from ===
-		from, endIdx := ParseFrom(tokens, pointer)
-		PrintPretty("END IDX===", endIdx)
-		pointer = endIdx + 1
-		// pointer = endIdx
-		ast.From = from
+	// === Parse from ===
+	from, endIdx, err := ParseFrom(tokens, pointer)
+	if err != nil {
+		ql.SetError(err)
+		return
 	}
+	pointer = endIdx + 1
+	ast.From = from
 
 	// === Expect WHERE
-	isNext, err = Expect(tokens[pointer], TokenWhere)
-
+	isNext, _ = Expect(tokens[pointer], TokenWhere)
 	if isNext {
 		// === Parse where ===
-		where, endIdx := ParseWhere(tokens, pointer+1)
+		where, endIdx, err := ParseWhere(tokens, pointer+1)
+		if err != nil {
+			ql.SetError(err)
+			return
+		}
 		ast.Where = where
 		pointer = endIdx
 	}
 
 	// === Expect GROUP BY
-	isNext, err = Expect(tokens[pointer], TokenGroupBy)
-
+	isNext, _ = Expect(tokens[pointer], TokenGroupBy)
 	if isNext {
 		// === Parse GROUP BY ===
-		groupBy, endIdx := ParseGroupBy(tokens, pointer+1)
+		groupBy, groupingSets, endIdx, err := ParseGroupBy(tokens, pointer+1, columns)
+		if err != nil {
+			ql.SetError(err)
+			return
+		}
 		ast.GroupBy = groupBy
+		ast.GroupingSets = groupingSets
+		pointer = endIdx
+	}
+
+	// === Expect HAVING ===
+	isNext, _ = Expect(tokens[pointer], TokenHaving)
+	if isNext {
+		having, endIdx, err := ParseHaving(tokens, pointer+1)
+		if err != nil {
+			ql.SetError(err)
+			return
+		}
+		ast.Having = having
 		pointer = endIdx
 	}
 
 	// === Expect ORDER BY ===
-	isNext, err = Expect(tokens[pointer], TokenOrderBy)
+	isNext, _ = Expect(tokens[pointer], TokenOrderBy)
 	if isNext {
-		orderBy, endIdx := ParseOrderBy(tokens, pointer)
+		orderBy, endIdx, err := ParseOrderBy(tokens, pointer+1)
+		if err != nil {
+			ql.SetError(err)
+			return
+		}
+		if err := ResolveOrderBy(orderBy, columns); err != nil {
+			ql.SetError(err)
+			return
+		}
+		if ast.Distinct {
+			if err := ValidateDistinctOrderBy(columns, orderBy); err != nil {
+				ql.SetError(err)
+				return
+			}
+		}
 		ast.OrderBy = orderBy
-		pointer = endIdx + 1
-		PrintPretty("pointer", pointer)
+		pointer = endIdx
 	}
 
-	// === Expect LIMIT ===
-	isNext, err = Expect(tokens[pointer], TokenLimit)
-	if isNext {
-		limit, endIdx := ParseLimit(tokens, pointer)
-		ast.Limit = limit
-		pointer = endIdx + 1
+	// === Expect LIMIT, OFFSET and FETCH ===
+	limit, offset, endIdx, err := ParseLimitOffset(tokens, pointer)
+	if err != nil {
+		ql.SetError(err)
+		return
+	}
+	ast.Limit = limit
+	ast.Offset = offset
+	pointer = endIdx
+
+	// === Expect end of query ===
+	isNext, err = Expect(tokens[pointer], TokenEOF)
+	if !isNext {
+		ql.SetError(err)
+		return
 	}
 
 	ql.Ast = ast
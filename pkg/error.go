@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Struct of syntax error raised by tokenizer and parsers
+type SyntaxError struct {
+	Pos      int
+	EndPos   int
+	Expected []TokenType
+	Found    TokenType
+	Text     string
+	Message  string
+	Sql      string
+}
+
+func NewSyntaxError(token Token, message string, expected ...TokenType) *SyntaxError {
+	endPos := token.EndPos
+	if endPos < token.Pos {
+		endPos = token.Pos
+	}
+	text := ""
+	if token.Value != nil {
+		text = Stringify(token.Value)
+	}
+	return &SyntaxError{
+		Pos:      token.Pos,
+		EndPos:   endPos,
+		Expected: expected,
+		Found:    token.Type,
+		Text:     text,
+		Message:  message,
+	}
+}
+
+// Error raised when a token of another kind is found
+func UnexpectedTokenError(token Token, expected ...TokenType) *SyntaxError {
+	return NewSyntaxError(token, "", expected...)
+}
+
+func (e *SyntaxError) Error() string {
+	message := e.Message
+	if len(message) == 0 {
+		found := e.Found.String()
+		if len(e.Text) > 0 && slices.Contains([]TokenType{TokenIdent, TokenNumber, TokenString}, e.Found) {
+			found = fmt.Sprintf("%v %q", found, e.Text)
+		}
+		if len(e.Expected) > 0 {
+			expected := []string{}
+			for _, tokenType := range e.Expected {
+				expected = append(expected, tokenType.String())
+			}
+			message = fmt.Sprintf("expected %v, found %v", strings.Join(expected, " or "), found)
+		} else {
+			message = fmt.Sprintf("unexpected %v", found)
+		}
+	}
+
+	result := fmt.Sprintf("Syntax error at position %d: %v", e.Pos, message)
+	excerpt := e.Excerpt()
+	if len(excerpt) > 0 {
+		result = fmt.Sprintf("%v\n%v", result, excerpt)
+	}
+	return result
+}
+
+// Render the line of query containing the error with a caret under the offending token
+func (e *SyntaxError) Excerpt() string {
+	if len(e.Sql) == 0 {
+		return ""
+	}
+	pos := min(max(e.Pos, 0), len(e.Sql))
+	endPos := min(max(e.EndPos, pos), len(e.Sql)-1)
+
+	lineStart := strings.LastIndex(e.Sql[:pos], "\n") + 1
+	lineEnd := strings.Index(e.Sql[lineStart:], "\n")
+	if lineEnd < 0 {
+		lineEnd = len(e.Sql)
+	} else {
+		lineEnd += lineStart
+	}
+	line := e.Sql[lineStart:lineEnd]
+
+	padding := []byte{}
+	for _, char := range e.Sql[lineStart:pos] {
+		if char == '\t' {
+			padding = append(padding, '\t')
+		} else {
+			padding = append(padding, ' ')
+		}
+	}
+	width := 1
+	if endPos >= pos && endPos < lineEnd {
+		width = max(utf8.RuneCountInString(e.Sql[pos:endPos+1]), 1)
+	}
+	return fmt.Sprintf("    %v\n    %v%v", line, string(padding), strings.Repeat("^", width))
+}
+
+// Attach query text to syntax error so it can render the excerpt
+func (ql *CSVQL) SetError(err error) {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		syntaxErr.Sql = ql.Sql
+	}
+	ql.Error = err
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Run query which must fail with syntax error
+func syntaxError(t *testing.T, sql string) *SyntaxError {
+	t.Helper()
+	ql := CSVQL{Sql: sql, DatabasePath: "testdata", Variables: map[string]string{}}
+	ql.Execute()
+	var syntaxErr *SyntaxError
+	if !errors.As(ql.Error, &syntaxErr) {
+		t.Fatalf("%v: got error %v, want syntax error", sql, ql.Error)
+	}
+	return syntaxErr
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		sql      string
+		pos      int
+		endPos   int
+		expected []TokenType
+		found    TokenType
+	}{
+		{"SELECT zip FROM", 15, 15, []TokenType{TokenIdent}, TokenEOF},
+		{"SELECT zip, FROM zips", 12, 15, nil, TokenFrom},
+		{"SELECT COUNT(zip FROM zips", 17, 20, []TokenType{TokenRParen}, TokenFrom},
+		{"SELECT zip FROM zips WHERE zip IN (1, 2", 39, 39, []TokenType{TokenComma, TokenRParen}, TokenEOF},
+		{"SELECT 'abc FROM zips", 7, 20, nil, TokenString},
+	}
+	for _, test := range tests {
+		err := syntaxError(t, test.sql)
+		if err.Pos != test.pos || err.EndPos != test.endPos || !reflect.DeepEqual(err.Expected, test.expected) || err.Found != test.found {
+			t.Errorf("%v: got Pos %d, EndPos %d, Expected %v, Found %v, want %d, %d, %v, %v",
+				test.sql, err.Pos, err.EndPos, err.Expected, err.Found, test.pos, test.endPos, test.expected, test.found)
+		}
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT zip FROM", "expected identifier, found end of query"},
+		{"SELECT COUNT(zip FROM zips", "expected ')', found FROM"},
+		{"SELECT zip FROM zips WHERE zip = = 3", "expected expression, found '='"},
+	}
+	for _, test := range tests {
+		firstLine, _, _ := strings.Cut(syntaxError(t, test.sql).Error(), "\n")
+		if !strings.HasSuffix(firstLine, test.want) {
+			t.Errorf("%v: got %q, want message %q", test.sql, firstLine, test.want)
+		}
+	}
+}
+
+func TestSyntaxErrorExcerpt(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			"SELECT zip, FROM zips",
+			"    SELECT zip, FROM zips\n" +
+				"                ^^^^",
+		},
+		{
+			"SELECT zip FROM",
+			"    SELECT zip FROM\n" +
+				"                   ^",
+		},
+		{
+			// Only line of error is rendered, caret is under token of that line
+			"SELECT zip\nFROM zips\nWHERE zip = = 3",
+			"    WHERE zip = = 3\n" +
+				"                ^",
+		},
+		{
+			// Tabs are kept in padding, so caret lines up however tabs are rendered
+			"SELECT zip FROM zips WHERE\tzip IN (1",
+			"    SELECT zip FROM zips WHERE\tzip IN (1\n" +
+				"                              \t         ^",
+		},
+	}
+	for _, test := range tests {
+		if got := syntaxError(t, test.sql).Excerpt(); got != test.want {
+			t.Errorf("%q:\n got\n%v\nwant\n%v", test.sql, got, test.want)
+		}
+	}
+
+	if got := (&SyntaxError{Pos: 3}).Excerpt(); got != "" {
+		t.Errorf("Excerpt without query = %q, want empty", got)
+	}
+}
+
+func TestExecuteAfterError(t *testing.T) {
+	// REPL reuses one CSVQL for every line, so failed query must not leak into next one
+	ql := CSVQL{DatabasePath: "testdata", Variables: map[string]string{}}
+	for _, sql := range []string{"SELECT zip FROM", "SELECT zip FROM missing", "SELECT zip FROM zips WHERE amount = 3"} {
+		ql.Sql = sql
+		ql.Execute()
+	}
+	if ql.Error != nil {
+		t.Fatalf("error of earlier query is kept: %v", ql.Error)
+	}
+	if want := [][]string{{"zip"}, {"02134"}}; !reflect.DeepEqual(ql.Result, want) {
+		t.Errorf("got %q, want %q", ql.Result, want)
+	}
+}
@@ -4,29 +4,17 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"log"
-	"math"
 	"os"
 	"path"
 	"slices"
-	"strconv"
 	"strings"
 )
 
-// GROUP BY
-type Collector []string
-type GroupByData map[string]Collector
-type GroupByRow struct {
-	Data  map[string][][]string
-	Order map[string][]int
-}
-
 // JOIN
 type ScanTableInfo struct {
 	HeaderRow   []string
 	HeaderIndex map[string]int
 	Rows        [][]string
-	GroupByRow  GroupByRow
 }
 
 type JoinHeaderIndex map[string][]int
@@ -39,6 +27,16 @@ func ParseHeaderIndex(header []string) map[string]int {
 	return headerIndex
 }
 
+// Add columns qualified by table alias or name, e.g. e.name, to header index of single table
+func QualifyHeaderIndex(headerIndex map[string]int, qualifier string) map[string]int {
+	qualified := map[string]int{}
+	for field, i := range headerIndex {
+		qualified[field] = i
+		qualified[qualifier+"."+field] = i
+	}
+	return qualified
+}
+
 func Compare(a, b int, op string) bool {
 	switch op {
 	case ">":
@@ -81,395 +79,403 @@ func FilterRow(row []string, headerIndex map[string]int, condition BinaryExpr) b
 	return true
 }
 
-// Select field in normal mode
-func SelectField(row []string, headerIndex map[string]int, columns []Column, headerRow []string) ([]string, []string) {
-	newRow := []string{}
-	newHeader := []string{}
-	if columns[0].Type == TokenStar {
-		return row, headerRow
+// Name of selected column: alias, then expression text, e.g. SUM(price * qty).
+// Column qualified by table is named by its field, e.g. e.name is "name"
+func ColumnName(col Column) string {
+	if len(col.Alias) > 0 {
+		return col.Alias
 	}
-	for _, col := range columns {
-		headerIdx := headerIndex[fmt.Sprintf("%v", col.Value)]
-		newRow = append(newRow, row[headerIdx])
-		newHeader = append(newHeader, Stringify(col.Value))
-		if len(col.Alias) > 0 {
-			newHeader[len(newHeader)-1] = Stringify(col.Alias)
+	if token, isToken := col.Expr.(Token); isToken && token.Type == TokenIdent {
+		if _, field, isQualified := strings.Cut(Stringify(token.Value), "."); isQualified {
+			return field
 		}
 	}
-	return newRow, newHeader
+	return Stringify(col.Value)
 }
 
-// Select field in GROUP BY mode
-func SelectGroupByField(row []string, headerIndex map[string]int, columns []Column, groupByMap map[string]GroupByData) ([]string, []string) {
-	newRow := []string{}
-	newHeader := []string{}
+// Header of selected columns
+func SelectHeader(columns []Column, headerRow []string) []string {
+	if columns[0].Type == TokenStar {
+		return headerRow
+	}
+	header := []string{}
 	for _, col := range columns {
-		headerIdx := headerIndex[fmt.Sprintf("%v", col.Value)]
-		if !IsAggregateFn(col) {
-			newHeader = append(newHeader, Stringify(col.Value))
-			newRow = append(newRow, row[headerIdx])
-		} else {
-			// aggregateFn := GetAggregateFn(col)
-			collector := GetAggregateCollector(groupByMap, row[headerIdx], Stringify(col.Value))
-			// PrintPretty("collector", collector)
-			aggregateVal := GetAggregateValue(col, collector)
-			newRow = append(newRow, aggregateVal)
-			newHeader = append(newHeader, GenerateAggregateColumnName(col))
+		header = append(header, ColumnName(col))
+	}
+	return header
+}
+
+// Get cell of selected column, plain column keeps raw text, expression is computed
+func SelectColumnValue(col Column, row []string, headerIndex map[string]int) (string, error) {
+	token, isToken := col.Expr.(Token)
+	if isToken && token.Type == TokenIdent {
+		fieldIdx, ok := headerIndex[Stringify(token.Value)]
+		if !ok || fieldIdx >= len(row) {
+			return "", fmt.Errorf(`Column "%v" does not exist`, token.Value)
 		}
+		return row[fieldIdx], nil
+	}
+	value, err := Eval(col.Expr, row, headerIndex)
+	if err != nil {
+		return "", err
+	}
+	return value.Cell(), nil
+}
 
-		if len(col.Alias) > 0 {
-			newHeader[len(newHeader)-1] = Stringify(col.Alias)
+// Cells of result row, NULL written by query is printed as empty cell
+func OutputRow(row []string) []string {
+	output := make([]string, len(row))
+	for i, cell := range row {
+		if cell != NullCell {
+			output[i] = cell
 		}
 	}
-	return newRow, newHeader
+	return output
 }
 
-// Select field in JOIN mode
-func SelectJoinField(row []string, headerIndex map[string]JoinHeaderIndex, columns []Column) ([]string, []string) {
-	header := []string{}
+// Select field in normal mode
+func SelectField(row []string, headerIndex map[string]int, columns []Column) ([]string, error) {
+	if len(columns) > 0 && columns[0].Type == TokenStar {
+		return row, nil
+	}
 	newRow := []string{}
 	for _, col := range columns {
-		valueArr, isArray := col.Value.([]string)
-
-		colName := Stringify(col.Value)
-		var headerIdx []int
-		if !isArray {
-			headerIdx = headerIndex["global"][colName]
-			if len(headerIdx) > 1 {
-				panic(fmt.Sprintf(`Column reference "%v" is ambiguous `, colName))
-			}
-		} else {
-			headerIdx = headerIndex[valueArr[0]][valueArr[1]]
-			colName = valueArr[1]
+		cell, err := SelectColumnValue(col, row, headerIndex)
+		if err != nil {
+			return nil, err
 		}
-		header = append(header, colName)
-		newRow = append(newRow, row[headerIdx[0]])
+		newRow = append(newRow, cell)
 	}
-	// return []string{}, []string{}
-	return newRow, header
+	return newRow, nil
 }
 
 // row1[field] - row2[field] < 0: asc
 // row2[field] - row1[field] > 0: desc
 // row1[field] - row2[field] == 0: remain
 // Process condition one by one. If row1[field]==row2[field] => process next condition
-func CompareNumber(a, b int) int {
-	return a - b
+func CompareNumber(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
 }
 
 func CompareString(a, b string) int {
 	return strings.Compare(a, b)
 }
 
+// Compare typed values, NULL sorts before everything
 func CompareProxy(a, b interface{}) int {
-	aNumber, aErr := strconv.Atoi(Stringify(a))
-	bNumber, bErr := strconv.Atoi(Stringify(b))
+	aValue := ToValue(a)
+	bValue := ToValue(b)
 
-	if aErr != nil || bErr != nil {
-		return CompareString(Stringify(a), Stringify(b))
+	cmp, isComparable := CompareValues(aValue, bValue)
+	if !isComparable {
+		return BooleanToInt(!aValue.IsNull()) - BooleanToInt(!bValue.IsNull())
 	}
-	return CompareNumber(aNumber, bNumber)
+	return cmp
 }
 
-func OrderByComparator(conditions []OrderBySingle, headerIndex map[string]int, row1, row2 []string) int {
-	for _, condition := range conditions {
-		field := condition.Field
-		direction := condition.Direction
-		fieldIdx := headerIndex[field]
-		row1Val := Stringify(row1[fieldIdx])
-		row2Val := Stringify(row2[fieldIdx])
+// Set of result rows for SELECT DISTINCT, rows are keyed by typed cell values so only keys are kept in memory
+type RowSet map[string]bool
 
-		if row1Val == row2Val {
+// Add row into set, return false when equal row was added before
+func (set RowSet) Add(row []string) bool {
+	values := []Value{}
+	for _, cell := range row {
+		values = append(values, CellValue(cell))
+	}
+	key := GroupKey(values)
+	if set[key] {
+		return false
+	}
+	set[key] = true
+	return true
+}
+
+// Row of result with its ORDER BY keys computed from source row
+type SortableRow struct {
+	Row  []string
+	Keys []Value
+}
+
+// Get ORDER BY keys of result row. Key resolved to output column, e.g. alias or position, uses output cell,
+// other expressions are computed from source row, so unselected columns work
+func EvalOrderByKeys(conditions []OrderBySingle, newRow []string, row []string, headerIndex map[string]int) ([]Value, error) {
+	keys := []Value{}
+	for _, condition := range conditions {
+		if condition.Column > 0 {
+			if condition.Column > len(newRow) {
+				return nil, fmt.Errorf("ORDER BY position %d is not in select list", condition.Column)
+			}
+			keys = append(keys, CellValue(newRow[condition.Column-1]))
 			continue
 		}
-
-		if direction == TokenAsc {
-			return CompareProxy(row1Val, row2Val)
+		key, err := Eval(condition.Expr, row, headerIndex)
+		if err != nil {
+			return nil, err
 		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
 
-		return CompareProxy(row2Val, row1Val)
+// Compare ORDER BY keys. NULLS FIRST and NULLS LAST place NULL regardless of direction
+func OrderByKeysComparator(conditions []OrderBySingle, row1, row2 SortableRow) int {
+	for i, condition := range conditions {
+		key1, key2 := row1.Keys[i], row2.Keys[i]
+		if condition.Nulls != 0 && key1.IsNull() != key2.IsNull() {
+			if key1.IsNull() == (condition.Nulls == TokenNullsFirst) {
+				return -1
+			}
+			return 1
+		}
+		cmp := CompareProxy(key1, key2)
+		if cmp == 0 {
+			continue
+		}
+		if condition.Direction == TokenDesc {
+			return -cmp
+		}
+		return cmp
 	}
 	return 0
 }
 
-func ScanTable(databasePath, name string, groupBy string) ScanTableInfo {
+// Read header and rows of table
+func ScanTable(databasePath, name string) (ScanTableInfo, error) {
 	filepath := path.Join(databasePath, fmt.Sprintf("%v.csv", name))
 	scanTableInfo := ScanTableInfo{}
-	groupByRow := GroupByRow{
-		Data:  map[string][][]string{},
-		Order: make(map[string][]int),
-	}
 
 	file, err := os.Open(filepath)
-
 	if err != nil {
-		panic(fmt.Sprintf("Table not found: %s", filepath))
+		return scanTableInfo, fmt.Errorf("Table not found: %v", name)
 	}
 	defer file.Close()
 
 	csvReader := csv.NewReader(file)
-	rowCount := 0
 	for {
 		row, err := csvReader.Read()
 		if err == io.EOF {
 			break // End of file reached
 		}
 		if err != nil {
-			// Handle error during reading
+			return scanTableInfo, fmt.Errorf("Failed to read table %v: %v", name, err)
+		}
+
+		// NUL byte marks NULL in rows built by query, so it can't be read as cell text
+		for i, cell := range row {
+			if strings.ContainsRune(cell, 0) {
+				line, column := csvReader.FieldPos(i)
+				return scanTableInfo, fmt.Errorf("Failed to read table %v: NUL byte in line %d, column %d", name, line, column)
+			}
 		}
 
 		if len(scanTableInfo.HeaderRow) == 0 {
-			// originalHeaderRow = row
 			scanTableInfo.HeaderRow = row
 			scanTableInfo.HeaderIndex = ParseHeaderIndex(scanTableInfo.HeaderRow)
 			continue
 		}
-
-		if len(groupBy) > 0 {
-			headerIdx, _ := scanTableInfo.HeaderIndex[groupBy]
-			groupByValue := row[headerIdx]
-
-			_, ok := groupByRow.Data[groupByValue]
-
-			if !ok {
-				groupByRow.Data[groupByValue] = [][]string{row}
-				groupByRow.Order[groupByValue] = []int{rowCount}
-			} else {
-				groupByRow.Data[groupByValue] = append(groupByRow.Data[groupByValue], row)
-				groupByRow.Order[groupByValue] = append(groupByRow.Order[groupByValue], rowCount)
-			}
-		}
-
-		// result = append(result, row)
 		scanTableInfo.Rows = append(scanTableInfo.Rows, row)
-		rowCount++
 	}
-	scanTableInfo.GroupByRow = groupByRow
-	return scanTableInfo
+	return scanTableInfo, nil
 }
 
-func HandleJoinTable(databasePath string, joinExpr JoinExpr) ([][]string, [][]string, map[string]JoinHeaderIndex, []string, GroupByRow) {
-	leftTableToken, isLeftSingleTable := joinExpr.Left.(Token)
-	rightTableToken, isRightSingleTable := joinExpr.Right.(Token)
-	leftScanTableInfo := ScanTableInfo{}
-	rightScanTableInfo := ScanTableInfo{}
-	joinHeader := []string{}
-	joinHeaderIndex := map[string]JoinHeaderIndex{
-		"global": make(JoinHeaderIndex),
-	}
-	leftGroupByRow := GroupByRow{}
-	columnIdx := 0
-
-	condition, _ := joinExpr.Condition.(BinaryExpr)
-
-	if isLeftSingleTable {
-		leftCondition, _ := condition.Left.(TableIdentifier)
-		tableName := Stringify(leftTableToken.Value)
-		groupByField := Stringify(leftCondition.Field.Value)
-
-		leftScanTableInfo = ScanTable(databasePath, tableName, groupByField)
-
-		joinHeader = append(joinHeader, leftScanTableInfo.HeaderRow...)
-		joinHeaderIndex[tableName] = make(JoinHeaderIndex)
-		for key, value := range leftScanTableInfo.HeaderIndex {
-			joinHeaderIndex["global"][key] = []int{value}
-			joinHeaderIndex[tableName][key] = []int{value}
-			columnIdx++
+// Scan rows of FROM as one table. Columns of single table can be qualified by its alias or name,
+// columns of joined tables referenced by query must not be ambiguous. Returns query with GROUP BY resolved against header
+func ScanSource(databasePath string, ast AST) (ScanTableInfo, AST, error) {
+	joinExpr, isJoin := ast.From.(JoinExpr)
+	if !isJoin {
+		from, _ := ast.From.(TableRef)
+		scanTableInfo, err := ScanTable(databasePath, Stringify(from.Name.Value))
+		if err != nil {
+			return scanTableInfo, ast, err
 		}
-		// PrintPretty("leftScanTableInfo", leftScanTableInfo)
-		leftGroupByRow = leftScanTableInfo.GroupByRow
-	} else {
-		joinExpr, _ := joinExpr.Left.(JoinExpr)
-		_, _rows, _joinHeaderIndex, _joinHeader, _groupByRow := HandleJoinTable(databasePath, joinExpr)
-		joinHeader = append(joinHeader, _joinHeader...)
-		leftScanTableInfo.Rows = _rows
-		leftScanTableInfo.HeaderRow = _joinHeader
-		leftGroupByRow = _groupByRow
-		// PrintPretty("_joinHeaderIndex", _joinHeaderIndex)
-		// PrintPretty("leftScanTableInfo", leftScanTableInfo)
-		// PrintPretty("leftGroupByRow", leftGroupByRow)
-		for groupKey, groupHash := range _joinHeaderIndex {
-			for key, value := range groupHash {
-				_, ok := joinHeaderIndex[groupKey]
-				if !ok {
-					joinHeaderIndex[groupKey] = make(JoinHeaderIndex)
-				}
-				joinHeaderIndex[groupKey][key] = value
-				if groupKey != "global" {
-					columnIdx++
-				}
-			}
+		scanTableInfo.HeaderIndex = QualifyHeaderIndex(scanTableInfo.HeaderIndex, from.Qualifier())
+		if err := ValidateStarOrderBy(ast.OrderBy, ast.Columns, scanTableInfo.HeaderRow); err != nil {
+			return scanTableInfo, ast, err
 		}
-
+		ast, err = ResolveGroupBy(ast, scanTableInfo.HeaderRow)
+		return scanTableInfo, ast, err
 	}
 
-	if isRightSingleTable {
-		rightCondition, _ := condition.Right.(TableIdentifier)
-		tableName := Stringify(rightTableToken.Value)
-		groupByField := Stringify(rightCondition.Field.Value)
-
-		rightScanTableInfo = ScanTable(databasePath, tableName, groupByField)
-
-		joinHeader = append(joinHeader, rightScanTableInfo.HeaderRow...)
-		joinHeaderIndex[tableName] = make(JoinHeaderIndex)
-		for key, value := range rightScanTableInfo.HeaderIndex {
-			_, ok := joinHeaderIndex["global"][key]
-			if !ok {
-				joinHeaderIndex["global"][key] = []int{value + columnIdx}
-			} else {
-				joinHeaderIndex["global"][key] = append(joinHeaderIndex["global"][key], value+columnIdx)
-			}
-			joinHeaderIndex[tableName][key] = []int{value + columnIdx}
-		}
-		// PrintPretty("rightScanTableInfo", rightScanTableInfo)
-	}
-
-	result := make([][]string, len(leftScanTableInfo.Rows))
-	for key, rows := range leftGroupByRow.Data {
-		rightRows := rightScanTableInfo.GroupByRow.Data[key]
-		rightOrders := rightScanTableInfo.GroupByRow.Order[key]
-		rowOrder := leftGroupByRow.Order[key]
-		for lIdx, lRow := range rows {
-			for _, rRow := range rightRows {
-				fullRow := append([]string{}, lRow...)
-				fullRow = append(fullRow, rRow...)
-				result[rowOrder[lIdx]] = fullRow
-				leftGroupByRow.Data[key][lIdx] = append(leftGroupByRow.Data[key][lIdx], rRow...)
-				leftGroupByRow.Order[key] = append(leftGroupByRow.Order[key], rightOrders...)
-			}
-		}
+	joinTable, err := HandleJoinTable(databasePath, joinExpr)
+	if err != nil {
+		return ScanTableInfo{}, ast, err
+	}
+	if err := ValidateStarOrderBy(ast.OrderBy, ast.Columns, joinTable.Header); err != nil {
+		return ScanTableInfo{}, ast, err
 	}
+	if ast, err = ResolveGroupBy(ast, joinTable.Header); err != nil {
+		return ScanTableInfo{}, ast, err
+	}
+	if err := joinTable.ValidateQueryColumns(ast); err != nil {
+		return ScanTableInfo{}, ast, err
+	}
+	return ScanTableInfo{
+		HeaderRow:   joinTable.Header,
+		HeaderIndex: joinTable.ExprHeaderIndex(),
+		Rows:        joinTable.Rows,
+	}, ast, nil
+}
 
-	notNullResult := [][]string{}
-	for _, row := range result {
-		if row == nil {
-			continue
-		}
-		notNullResult = append(notNullResult, row)
+// Project source row into result row with its ORDER BY keys, returns false when row is dropped by DISTINCT
+func ProjectRow(ast AST, row []string, headerIndex map[string]int, distinctRows RowSet) (SortableRow, bool, error) {
+	newRow, err := SelectField(row, headerIndex, ast.Columns)
+	if err != nil {
+		return SortableRow{}, false, err
+	}
+	if ast.Distinct && !distinctRows.Add(newRow) {
+		return SortableRow{}, false, nil
 	}
-	result = append([][]string{joinHeader}, notNullResult...)
-	return result, notNullResult, joinHeaderIndex, joinHeader, leftGroupByRow
+	keys, err := EvalOrderByKeys(ast.OrderBy, newRow, row, headerIndex)
+	if err != nil {
+		return SortableRow{}, false, err
+	}
+	return SortableRow{Row: newRow, Keys: keys}, true, nil
 }
 
 func (ql *CSVQL) ExecuteAST() {
 	ast := ql.Ast
 	databasePath := ql.DatabasePath
-	originalHeaderRow := []string{}
-	headerRow := []string{}
-	headerIndex := map[string]int{}
 	result := [][]string{}
-	groupByMap := map[string]GroupByData{}
-
-	groupByTable := [][]string{}
-	groupByHeader := []string{}
+	sortableRows := []SortableRow{}
+	distinctRows := RowSet{}
 
-	joinTable := [][]string{}
-	joinHeaderIndex := map[string]JoinHeaderIndex{}
-	joinHeader := []string{}
+	// Window functions need all source rows, so rows are projected after computing them
+	windows := WindowSources(ast)
+	sourceRows := [][]string{}
+	sourceIndex := map[string]int{}
 
-	joinExpr, isJoin := ast.From.(JoinExpr)
-	if !isJoin {
-		from, _ := ast.From.(Token)
-		// Handle FROM statement
-		filepath := path.Join(databasePath, fmt.Sprintf("%v.csv", from.Value))
-		fmt.Println(filepath)
-		file, err := os.Open(filepath)
-		if err != nil {
-			log.Fatalln("File not found.")
-		}
-
-		csvReader := csv.NewReader(file)
-		for {
-			row, err := csvReader.Read()
-			if err == io.EOF {
-				break // End of file reached
-			}
+	// Handle FROM statement, joined rows are filtered, grouped and projected as rows of one table
+	source, ast, err := ScanSource(databasePath, ast)
+	if err != nil {
+		ql.SetError(err)
+		return
+	}
+	isAggregate := IsAggregateQuery(ast)
+	aggregate := NewHashAggregate(ast.GroupBy, ast.GroupingSets, AggregateSources(ast))
+	originalHeaderRow := source.HeaderRow
+	headerIndex := source.HeaderIndex
+	headerRow := SelectHeader(ast.Columns, originalHeaderRow)
+
+	for _, row := range source.Rows {
+		//Handle WHERE statement, after join so it sees NULLs of outer join
+		if ast.Where != nil {
+			isValid, err := Eval(ast.Where, row, headerIndex)
 			if err != nil {
-				// Handle error during reading
+				ql.SetError(err)
+				return
 			}
-
-			if len(originalHeaderRow) == 0 {
-				originalHeaderRow = row
-				headerIndex = ParseHeaderIndex(originalHeaderRow)
+			if !isValid.IsTrue() {
 				continue
 			}
+		}
 
-			var isValid interface{}
-
-			//Handle WHERE statement
-			if ast.Where != nil {
-				isValid = Eval(ast.Where, row, headerIndex)
+		if !isAggregate && len(windows) > 0 {
+			sourceRows = append(sourceRows, row)
+			sourceIndex = headerIndex
+		} else if !isAggregate { // In case no aggregation => Process SELECT columns
+			//Handle SELECT statement
+			sortableRow, isKept, err := ProjectRow(ast, row, headerIndex, distinctRows)
+			if err != nil {
+				ql.SetError(err)
+				return
 			}
-			if isValid == 0 {
-				continue
+			if isKept {
+				sortableRows = append(sortableRows, sortableRow)
+			}
+		} else { // In case aggregation => Add row into its group
+			if err := aggregate.Add(row, headerIndex); err != nil {
+				ql.SetError(err)
+				return
 			}
+		}
+	}
 
-			if ast.GroupBy == nil { // In case no GROUP BY => Process SELECT columns
-				//Handle SELECT statement
-				newRow, newHeader := SelectField(row, headerIndex, ast.Columns, originalHeaderRow)
-				result = append(result, newRow)
-
-				headerRow = newHeader
-			} else { // In case GROUP BY => Process groupByMap and groupByTable
-				groupByFields, groupByData, otherFields, otherData, key := ProcessGroupByPerRow(row, originalHeaderRow, headerIndex, ast.GroupBy)
-				isGrouped := AppendGroupByData(groupByMap, key, otherFields, otherData)
-				if !isGrouped {
-					groupByTable = append(groupByTable, BuildGroupByRow(groupByFields, groupByData, originalHeaderRow, key))
+	if isAggregate {
+		if err := aggregate.EnsureWholeTableGroup(); err != nil {
+			ql.SetError(err)
+			return
+		}
+		width := len(originalHeaderRow)
+		groupHeaderIndex := aggregate.GroupHeaderIndex(headerIndex, width)
+		// HAVING can refer to output columns, except window function results computed later
+		havingColumns := NonWindowColumns(ast.Columns)
+		havingIndex := HavingHeaderIndex(groupHeaderIndex, havingColumns, aggregate.GroupRowWidth(width))
+		for _, group := range aggregate.AllGroups() {
+			groupRow := aggregate.GroupRow(group, width)
+			//Handle HAVING statement
+			if ast.Having != nil {
+				havingRow, err := SelectField(groupRow, groupHeaderIndex, havingColumns)
+				if err != nil {
+					ql.SetError(err)
+					return
 				}
-				fmt.Println("key", groupByFields, groupByData, otherFields, otherData, key)
+				isValid, err := Eval(ast.Having, append(groupRow, havingRow...), havingIndex)
+				if err != nil {
+					ql.SetError(err)
+					return
+				}
+				if !isValid.IsTrue() {
+					continue
+				}
+			}
+			if len(windows) > 0 {
+				sourceRows = append(sourceRows, groupRow)
+				sourceIndex = groupHeaderIndex
+				continue
+			}
+			sortableRow, isKept, err := ProjectRow(ast, groupRow, groupHeaderIndex, distinctRows)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
+			if isKept {
+				sortableRows = append(sortableRows, sortableRow)
 			}
 		}
-	} else {
-		joinTable, _, joinHeaderIndex, _, _ = HandleJoinTable(databasePath, joinExpr)
-		// PrintPretty("joinHeaderIndex", joinHeaderIndex)
 	}
 
-	if ast.GroupBy != nil {
-		for _, row := range groupByTable {
-			newRow, newHeader := SelectGroupByField(row, headerIndex, ast.Columns, groupByMap)
-			result = append(result, newRow)
-
-			if len(groupByHeader) == 0 {
-				groupByHeader = newHeader
-			}
+	//Handle window functions, after WHERE, GROUP BY and HAVING, before ORDER BY and LIMIT
+	if len(windows) > 0 {
+		windowIndex, err := ComputeWindows(windows, sourceRows, sourceIndex)
+		if err != nil {
+			ql.SetError(err)
+			return
 		}
-		// result = append([][]string{groupByHeader}, result...)
-	} else if isJoin {
-		// PrintPretty("joinTable", joinTable)
-		for i := 1; i < len(joinTable); i++ {
-			row := joinTable[i]
-			newRow, newHeader := SelectJoinField(row, joinHeaderIndex, ast.Columns)
-			result = append(result, newRow)
-
-			if len(joinHeader) == 0 {
-				joinHeader = newHeader
-				headerIndex = ParseHeaderIndex(newHeader)
+		for _, row := range sourceRows {
+			sortableRow, isKept, err := ProjectRow(ast, row, windowIndex, distinctRows)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
+			if isKept {
+				sortableRows = append(sortableRows, sortableRow)
 			}
-
 		}
 	}
 
 	if ast.OrderBy != nil {
-		slices.SortFunc(result, func(row1, row2 []string) int {
-			return OrderByComparator(ast.OrderBy, headerIndex, row1, row2)
+		slices.SortStableFunc(sortableRows, func(row1, row2 SortableRow) int {
+			return OrderByKeysComparator(ast.OrderBy, row1, row2)
 		})
 	}
+	for _, sortableRow := range sortableRows {
+		result = append(result, OutputRow(sortableRow.Row))
+	}
 
-	if ast.Limit > 0 {
-		maxLimit := int(math.Min(float64(ast.Limit), float64(len(result))))
-		result = result[0:maxLimit]
+	//Handle OFFSET and LIMIT statement
+	result = result[min(ast.Offset, len(result)):]
+	if ast.Limit >= 0 {
+		result = result[:min(ast.Limit, len(result))]
 	}
 
 	//Adding result header
-	if ast.GroupBy != nil {
-		result = append([][]string{groupByHeader}, result...)
-	} else if isJoin {
-		result = append([][]string{joinHeader}, result...)
-	} else {
-		result = append([][]string{headerRow}, result...)
-	}
-	// PrintPretty("GroupByMap", groupByMap)
-	// PrintPretty("GroupByTable", groupByTable)
+	result = append([][]string{headerRow}, result...)
 	// PrintPretty("result: ", result)
 	// fmt.Println("result:", result)
 	// return nil
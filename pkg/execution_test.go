@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Run query against tables in testdata, fail test on error
+func executeQuery(t *testing.T, sql string) [][]string {
+	t.Helper()
+	ql := CSVQL{Sql: sql, DatabasePath: "testdata", Variables: map[string]string{}}
+	ql.Execute()
+	if ql.Error != nil {
+		t.Fatalf("%v: %v", sql, ql.Error)
+	}
+	return ql.Result
+}
+
+// Run query which must fail, return its error
+func executeError(t *testing.T, sql string) error {
+	t.Helper()
+	ql := CSVQL{Sql: sql, DatabasePath: "testdata", Variables: map[string]string{}}
+	ql.Execute()
+	if ql.Error == nil {
+		t.Fatalf("%v: got %q, want error", sql, ql.Result)
+	}
+	return ql.Error
+}
+
+type queryTest struct {
+	sql  string
+	want [][]string
+}
+
+func runQueryTests(t *testing.T, tests []queryTest) {
+	t.Helper()
+	for _, test := range tests {
+		if got := executeQuery(t, test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v:\n got %q\nwant %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestExecuteKeepsCellText(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT zip FROM zips WHERE zip = '00501'",
+			[][]string{{"zip"}, {"00501"}},
+		},
+		{
+			"SELECT zip FROM zips WHERE code = '1000'",
+			[][]string{{"zip"}, {"00501"}, {"02134"}},
+		},
+		{
+			"SELECT zip FROM zips WHERE zip = 501",
+			[][]string{{"zip"}, {"00501"}, {"501"}},
+		},
+		{
+			"SELECT LENGTH(zip), zip || '-', zip + 1 FROM zips LIMIT 1",
+			[][]string{{"LENGTH(zip)", "zip || '-'", "zip + 1"}, {"5", "00501-", "502"}},
+		},
+		{
+			"SELECT DISTINCT code FROM zips",
+			[][]string{{"code"}, {"1000"}, {"1e3"}},
+		},
+		{
+			"SELECT code, COUNT(*) FROM zips GROUP BY code",
+			[][]string{{"code", "COUNT(*)"}, {"1000", "2"}, {"1e3", "1"}},
+		},
+		{
+			"SELECT MIN(zip), COUNT(DISTINCT code) FROM zips",
+			[][]string{{"MIN(zip)", "COUNT(DISTINCT code)"}, {"00501", "2"}},
+		},
+	})
+}
+
+func TestExecuteNullMarkers(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT zip FROM zips WHERE region = 'NA'",
+			[][]string{{"zip"}, {"00501"}},
+		},
+		{
+			"SELECT COUNT(region) FROM zips",
+			[][]string{{"COUNT(region)"}, {"3"}},
+		},
+	})
+
+	setNullMarkers(t, "NULL", "NA")
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT zip, COALESCE(region, 'none') FROM zips WHERE region IS NULL",
+			[][]string{{"zip", "COALESCE(region, 'none')"}, {"00501", "none"}},
+		},
+		{
+			"SELECT COUNT(region), COUNT(*), SUM(amount) FROM zips WHERE region IS NOT NULL",
+			[][]string{{"COUNT(region)", "COUNT(*)", "SUM(amount)"}, {"2", "2", "5"}},
+		},
+	})
+}
+
+func TestExecuteNulByte(t *testing.T) {
+	// NUL byte would be read as NULL cell of query, so table with one is rejected
+	want := "Failed to read table nul_bytes: NUL byte in line 2, column 3"
+	if err := executeError(t, "SELECT id FROM nul_bytes WHERE id = 2"); err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func TestExecuteRollupNullMarkers(t *testing.T) {
+	// Subtotal columns are NULL whatever NULL markers are set
+	setNullMarkers(t, "NULL")
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT COALESCE(region, 'TOTAL'), SUM(amount) FROM zips GROUP BY ROLLUP(region)",
+			[][]string{{"COALESCE(region, 'TOTAL')", "SUM(amount)"}, {"NA", "1"}, {"EU", "2"}, {"na", "3"}, {"TOTAL", "6"}},
+		},
+		{
+			"SELECT region, GROUPING(region), COUNT(*) FROM zips GROUP BY ROLLUP(region) HAVING region IS NULL",
+			[][]string{{"region", "GROUPING(region)", "COUNT(*)"}, {"", "1", "3"}},
+		},
+	})
+}
+
+func TestExecuteKeywordColumns(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT id, end FROM keywords WHERE end > 4",
+			[][]string{{"id", "end"}, {"1", "5"}},
+		},
+		{
+			"SELECT CASE WHEN end > 4 THEN end ELSE keywords.end * 10 END AS e FROM keywords ORDER BY end",
+			[][]string{{"e"}, {"30"}, {"5"}},
+		},
+		{
+			"SELECT CASE WHEN id = 1 THEN CASE WHEN end > 4 THEN 'high' END ELSE 'low' END FROM keywords",
+			[][]string{{"CASE WHEN id = 1 THEN CASE WHEN end > 4 THEN 'high' END ELSE 'low' END"}, {"high"}, {"low"}},
+		},
+		{
+			"SELECT range, rows, over FROM keywords WHERE rows > 100 ORDER BY range",
+			[][]string{{"range", "rows", "over"}, {"20", "200", "8"}},
+		},
+		{
+			"SELECT id, SUM(rows) OVER (ORDER BY range ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM keywords",
+			[][]string{{"id", "SUM(rows) OVER (ORDER BY range ROWS BETWEEN 1 PRECEDING AND CURRENT ROW)"}, {"1", "100"}, {"2", "300"}},
+		},
+		{
+			"SELECT id, offset FROM keywords ORDER BY offset DESC LIMIT 1 OFFSET 1",
+			[][]string{{"id", "offset"}, {"1", "0"}},
+		},
+		{
+			"SELECT offset + 1 AS next FROM keywords k WHERE k.offset > 0 OFFSET 0 ROWS",
+			[][]string{{"next"}, {"2"}},
+		},
+		{
+			"SELECT o.id FROM keywords offset JOIN keywords o ON offset.id = o.offset WHERE o.id = offset.offset + 2",
+			[][]string{{"id"}, {"2"}},
+		},
+	})
+}
+
+func TestExecuteOffsetWithoutCount(t *testing.T) {
+	// OFFSET is keyword at end of FROM clause and after LIMIT, so missing count is reported
+	tests := []struct {
+		sql   string
+		pos   int
+		found TokenType
+	}{
+		{"SELECT id FROM keywords OFFSET", 30, TokenEOF},
+		{"SELECT id FROM keywords LIMIT 1 OFFSET", 38, TokenEOF},
+		{"SELECT id FROM keywords LIMIT 1 OFFSET offset", 39, TokenOffset},
+		{"SELECT id FROM keywords ORDER BY id DESC OFFSET", 47, TokenEOF},
+	}
+	for _, test := range tests {
+		err := syntaxError(t, test.sql)
+		if !reflect.DeepEqual(err.Expected, []TokenType{TokenNumber}) || err.Pos != test.pos || err.Found != test.found {
+			t.Errorf("%v: got %v, want number expected at %d", test.sql, err, test.pos)
+		}
+	}
+}
+
+func TestExecuteGroupByAlias(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT LENGTH(zip) AS len, COUNT(*) FROM zips GROUP BY len",
+			[][]string{{"len", "COUNT(*)"}, {"5", "2"}, {"3", "1"}},
+		},
+		{
+			"SELECT c.name AS who, COUNT(*) FROM customers c JOIN orders o ON c.id = o.cust_id GROUP BY ROLLUP(who) ORDER BY who",
+			[][]string{{"who", "COUNT(*)"}, {"", "4"}, {"Ann", "3"}, {"Ben", "1"}},
+		},
+		{
+			// Source column code takes precedence over alias
+			"SELECT MIN(zip) AS code, COUNT(*) FROM zips GROUP BY code",
+			[][]string{{"code", "COUNT(*)"}, {"00501", "2"}, {"501", "1"}},
+		},
+	})
+
+	// Column region is not grouped
+	executeError(t, "SELECT region AS code, COUNT(*) FROM zips GROUP BY code")
+}
+
+func TestExecuteNumberLiteralNames(t *testing.T) {
+	// Columns are named by number literals as written, values are normalized
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT 1.5e3, 007, -2.50, 1.5e3 * 2, amount + 1.0 FROM sales LIMIT 1",
+			[][]string{{"1.5e3", "007", "-2.50", "1.5e3 * 2", "amount + 1.0"}, {"1500", "7", "-2.50", "3000", "11.0"}},
+		},
+		{
+			"SELECT amount * 1e1 FROM sales ORDER BY amount * 1e1 DESC LIMIT 2",
+			[][]string{{"amount * 1e1"}, {"400"}, {"300"}},
+		},
+	})
+}
+
+func TestExecuteStarOrderByPosition(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{"SELECT * FROM customers ORDER BY 2 DESC", [][]string{{"id", "name"}, {"3", "Cid"}, {"2", "Ben"}, {"1", "Ann"}}},
+	})
+
+	// Position past source columns is reported at number before any row is read
+	tests := []struct {
+		sql  string
+		pos  int
+		want string
+	}{
+		{"SELECT * FROM customers ORDER BY 99", 33, "ORDER BY position 99 is not in select list"},
+		{"SELECT * FROM customers ORDER BY name, 3 DESC", 39, "ORDER BY position 3 is not in select list"},
+		{"SELECT * FROM customers c JOIN orders o ON c.id = o.cust_id ORDER BY 6", 69, "ORDER BY position 6 is not in select list"},
+	}
+	for _, test := range tests {
+		err := syntaxError(t, test.sql)
+		if err.Message != test.want || err.Pos != test.pos || err.Found != TokenNumber {
+			t.Errorf("%v: got %v at %d, want %q at %d", test.sql, err, err.Pos, test.want, test.pos)
+		}
+	}
+}
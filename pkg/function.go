@@ -0,0 +1,495 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Struct of scalar function call
+type FunctionExpr struct {
+	Name     Token
+	Args     []Expr
+	Function ScalarFunction
+}
+
+// Expected type of function argument or type of function result
+type ArgType int
+
+const (
+	ArgAny ArgType = iota
+	ArgString
+	ArgNumber
+	ArgInteger
+	ArgTemporal
+	ArgDatePart
+	ArgTruncUnit
+)
+
+type ScalarFunction struct {
+	Name       string
+	MinArgs    int
+	MaxArgs    int       // -1 means variadic
+	ArgTypes   []ArgType // Type of each argument, last one repeats for variadic
+	ReturnType ArgType
+	IsNullSafe bool // Call receives NULL arguments, otherwise any NULL argument gives NULL
+	Call       func(args []Value) (Value, error)
+}
+
+var scalarFunctions = map[string]ScalarFunction{}
+
+// Add function to registry, lookup is case-insensitive
+func RegisterScalarFunction(function ScalarFunction) {
+	scalarFunctions[function.Name] = function
+}
+
+func init() {
+	// === Conditional ===
+	RegisterScalarFunction(ScalarFunction{Name: "COALESCE", MinArgs: 1, MaxArgs: -1, IsNullSafe: true, Call: Coalesce})
+	RegisterScalarFunction(ScalarFunction{Name: "IFNULL", MinArgs: 2, MaxArgs: 2, IsNullSafe: true, Call: Coalesce})
+	RegisterScalarFunction(ScalarFunction{Name: "NULLIF", MinArgs: 2, MaxArgs: 2, IsNullSafe: true, Call: NullIf})
+	RegisterScalarFunction(ScalarFunction{Name: "GREATEST", MinArgs: 1, MaxArgs: -1, IsNullSafe: true, Call: Greatest})
+	RegisterScalarFunction(ScalarFunction{Name: "LEAST", MinArgs: 1, MaxArgs: -1, IsNullSafe: true, Call: Least})
+	RegisterScalarFunction(ScalarFunction{Name: "CAST", MinArgs: 2, MaxArgs: 4, ArgTypes: []ArgType{ArgAny, ArgString, ArgInteger}, Call: Cast})
+
+	// === String ===
+	RegisterScalarFunction(ScalarFunction{Name: "UPPER", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: Upper})
+	RegisterScalarFunction(ScalarFunction{Name: "LOWER", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: Lower})
+	RegisterScalarFunction(ScalarFunction{Name: "TRIM", MinArgs: 1, MaxArgs: 2, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: Trim})
+	RegisterScalarFunction(ScalarFunction{Name: "LTRIM", MinArgs: 1, MaxArgs: 2, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: LTrim})
+	RegisterScalarFunction(ScalarFunction{Name: "RTRIM", MinArgs: 1, MaxArgs: 2, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: RTrim})
+	RegisterScalarFunction(ScalarFunction{Name: "LENGTH", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgString}, ReturnType: ArgInteger, Call: Length})
+	RegisterScalarFunction(ScalarFunction{Name: "SUBSTR", MinArgs: 2, MaxArgs: 3, ArgTypes: []ArgType{ArgString, ArgInteger, ArgInteger}, ReturnType: ArgString, Call: Substr})
+	RegisterScalarFunction(ScalarFunction{Name: "SUBSTRING", MinArgs: 2, MaxArgs: 3, ArgTypes: []ArgType{ArgString, ArgInteger, ArgInteger}, ReturnType: ArgString, Call: Substr})
+	RegisterScalarFunction(ScalarFunction{Name: "REPLACE", MinArgs: 3, MaxArgs: 3, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: Replace})
+	RegisterScalarFunction(ScalarFunction{Name: "SPLIT_PART", MinArgs: 3, MaxArgs: 3, ArgTypes: []ArgType{ArgString, ArgString, ArgInteger}, ReturnType: ArgString, Call: SplitPart})
+	RegisterScalarFunction(ScalarFunction{Name: "STRPOS", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgString}, ReturnType: ArgInteger, Call: StrPos})
+	RegisterScalarFunction(ScalarFunction{Name: "REVERSE", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, Call: Reverse})
+	RegisterScalarFunction(ScalarFunction{Name: "LPAD", MinArgs: 2, MaxArgs: 3, ArgTypes: []ArgType{ArgString, ArgInteger, ArgString}, ReturnType: ArgString, Call: LPad})
+	RegisterScalarFunction(ScalarFunction{Name: "RPAD", MinArgs: 2, MaxArgs: 3, ArgTypes: []ArgType{ArgString, ArgInteger, ArgString}, ReturnType: ArgString, Call: RPad})
+	RegisterScalarFunction(ScalarFunction{Name: "CONCAT", MinArgs: 1, MaxArgs: -1, ArgTypes: []ArgType{ArgString}, ReturnType: ArgString, IsNullSafe: true, Call: Concat})
+
+	// === Math ===
+	RegisterScalarFunction(ScalarFunction{Name: "ABS", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Abs})
+	RegisterScalarFunction(ScalarFunction{Name: "ROUND", MinArgs: 1, MaxArgs: 2, ArgTypes: []ArgType{ArgNumber, ArgInteger}, ReturnType: ArgNumber, Call: Round})
+	RegisterScalarFunction(ScalarFunction{Name: "FLOOR", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Floor})
+	RegisterScalarFunction(ScalarFunction{Name: "CEIL", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Ceil})
+	RegisterScalarFunction(ScalarFunction{Name: "CEILING", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Ceil})
+	RegisterScalarFunction(ScalarFunction{Name: "SIGN", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgInteger, Call: Sign})
+	RegisterScalarFunction(ScalarFunction{Name: "MOD", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Mod})
+	RegisterScalarFunction(ScalarFunction{Name: "POWER", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Power})
+	RegisterScalarFunction(ScalarFunction{Name: "SQRT", MinArgs: 1, MaxArgs: 1, ArgTypes: []ArgType{ArgNumber}, ReturnType: ArgNumber, Call: Sqrt})
+
+	// === Date ===
+	RegisterScalarFunction(ScalarFunction{Name: "NOW", MinArgs: 0, MaxArgs: 0, ReturnType: ArgTemporal, Call: Now})
+	RegisterScalarFunction(ScalarFunction{Name: "CURRENT_DATE", MinArgs: 0, MaxArgs: 0, ReturnType: ArgTemporal, Call: CurrentDate})
+	RegisterScalarFunction(ScalarFunction{Name: "DATE_TRUNC", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgTruncUnit, ArgTemporal}, ReturnType: ArgTemporal, Call: DateTrunc})
+	RegisterScalarFunction(ScalarFunction{Name: "DATE_PART", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgDatePart, ArgTemporal}, ReturnType: ArgInteger, Call: DatePart})
+	RegisterScalarFunction(ScalarFunction{Name: "EXTRACT", MinArgs: 2, MaxArgs: 2, ArgTypes: []ArgType{ArgDatePart, ArgTemporal}, ReturnType: ArgInteger, Call: DatePart})
+}
+
+// Find scalar function by case-insensitive name
+func GetScalarFunction(name string) (ScalarFunction, bool) {
+	function, ok := scalarFunctions[strings.ToUpper(name)]
+	return function, ok
+}
+
+// Validate function call against registry at parse time
+func NewFunctionExpr(name Token, args []Expr) (FunctionExpr, error) {
+	function, ok := GetScalarFunction(Stringify(name.Value))
+	if !ok {
+		return FunctionExpr{}, NewSyntaxError(name, fmt.Sprintf("unknown function %v", name.Value))
+	}
+	if len(args) < function.MinArgs || (function.MaxArgs >= 0 && len(args) > function.MaxArgs) {
+		return FunctionExpr{}, NewSyntaxError(name, fmt.Sprintf("function %v expects %v, got %d", function.Name, FormatArity(function.MinArgs, function.MaxArgs), len(args)))
+	}
+	for idx, arg := range args {
+		if err := CheckStaticArg(function, idx, arg); err != nil {
+			position := name
+			if token, isToken := arg.(Token); isToken {
+				position = token
+			}
+			return FunctionExpr{}, NewSyntaxError(position, err.Error())
+		}
+	}
+	functionExpr := FunctionExpr{
+		Name:     name,
+		Args:     args,
+		Function: function,
+	}
+
+	// Call with only literal arguments is computed once so invalid values are reported early, e.g. CAST('abc' AS INT)
+	if IsLiteralArgs(args) {
+		if _, err := ComputeFunction(functionExpr, nil, nil); err != nil {
+			return FunctionExpr{}, NewSyntaxError(name, err.Error())
+		}
+	}
+	return functionExpr, nil
+}
+
+func FormatArity(minArgs, maxArgs int) string {
+	noun := "arguments"
+	if maxArgs == 1 || (maxArgs < 0 && minArgs == 1) {
+		noun = "argument"
+	}
+	switch {
+	case maxArgs < 0:
+		return fmt.Sprintf("at least %d %v", minArgs, noun)
+	case minArgs == maxArgs:
+		return fmt.Sprintf("%d %v", minArgs, noun)
+	default:
+		return fmt.Sprintf("%d to %d %v", minArgs, maxArgs, noun)
+	}
+}
+
+func (argType ArgType) String() string {
+	switch argType {
+	case ArgString:
+		return "string"
+	case ArgNumber:
+		return "number"
+	case ArgInteger:
+		return "integer"
+	case ArgTemporal:
+		return "date or timestamp"
+	case ArgDatePart:
+		return "date part"
+	case ArgTruncUnit:
+		return "date unit"
+	default:
+		return "any"
+	}
+}
+
+// Expected type of argument at index
+func (function ScalarFunction) ArgType(idx int) ArgType {
+	if len(function.ArgTypes) == 0 {
+		return ArgAny
+	}
+	return function.ArgTypes[min(idx, len(function.ArgTypes)-1)]
+}
+
+// Whether a result of type got can be passed where type want is expected.
+// Strings may hold numbers or dates, so only number and date are incompatible
+func IsArgTypeCompatible(want, got ArgType) bool {
+	isNumber := func(argType ArgType) bool {
+		return argType == ArgNumber || argType == ArgInteger
+	}
+	switch {
+	case isNumber(want):
+		return got != ArgTemporal
+	case want == ArgTemporal:
+		return !isNumber(got)
+	default:
+		return true
+	}
+}
+
+// Check argument whose type is known while parsing: literal or result of other function
+func CheckStaticArg(function ScalarFunction, idx int, arg Expr) error {
+	switch typed := arg.(type) {
+	case Token:
+		if typed.Type == TokenIdent || typed.Type == TokenNull {
+			return nil
+		}
+		_, err := CoerceArg(function, idx, TokenToValue(typed))
+		return err
+	case FunctionExpr:
+		want := function.ArgType(idx)
+		if !IsArgTypeCompatible(want, typed.Function.ReturnType) {
+			return fmt.Errorf("function %v expects %v as argument %d, got %v from %v", function.Name, want, idx+1, typed.Function.ReturnType, typed.Function.Name)
+		}
+	}
+	return nil
+}
+
+func IsLiteralArgs(args []Expr) bool {
+	for _, arg := range args {
+		token, isToken := arg.(Token)
+		if !isToken || token.Type == TokenIdent {
+			return false
+		}
+	}
+	return true
+}
+
+// Convert argument value into expected type, e.g. numeric string into number
+func CoerceArg(function ScalarFunction, idx int, value Value) (Value, error) {
+	argType := function.ArgType(idx)
+	coerced, ok := value, true
+	switch argType {
+	case ArgString:
+		coerced = StringValue(value.String())
+	case ArgNumber:
+		coerced = CoerceNumeric(value)
+		ok = coerced.IsNumeric()
+	case ArgInteger:
+		coerced, ok = ToInteger(CoerceNumeric(value))
+	case ArgTemporal:
+		if value.Kind == KindString {
+			coerced = CoerceString(value, KindTimestamp)
+		}
+		ok = coerced.IsTemporal()
+	case ArgDatePart, ArgTruncUnit:
+		coerced = StringValue(strings.ToLower(value.String()))
+		ok = value.Kind == KindString && IsDateUnit(argType, coerced.Str)
+	}
+	if !ok {
+		got := value.Kind.String()
+		if value.Kind == KindString {
+			got = fmt.Sprintf("'%v'", value.Str)
+		}
+		return NullValue(), fmt.Errorf("function %v expects %v as argument %d, got %v", function.Name, argType, idx+1, got)
+	}
+	return coerced, nil
+}
+
+// Handle compute for function call
+func ComputeFunction(ast FunctionExpr, row []string, headerIndex map[string]int) (Value, error) {
+	args := []Value{}
+	for idx, arg := range ast.Args {
+		value, err := Eval(arg, row, headerIndex)
+		if err != nil {
+			return NullValue(), err
+		}
+		if value.IsNull() {
+			if !ast.Function.IsNullSafe {
+				return NullValue(), nil
+			}
+			args = append(args, value)
+			continue
+		}
+		value, err = CoerceArg(ast.Function, idx, value)
+		if err != nil {
+			return NullValue(), err
+		}
+		args = append(args, value)
+	}
+	return ast.Function.Call(args)
+}
+
+// Handle parse CAST(expr AS type), type becomes string argument followed by precision and scale of DECIMAL(p, s)
+func (p *Parser) ParseCastArguments() ([]Expr, error) {
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	expr, err := p.ParseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(TokenAs); err != nil {
+		return nil, err
+	}
+	typeToken, err := p.Expect(TokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	typeName := strings.ToUpper(Stringify(typeToken.Value))
+	if _, ok := castTypes[typeName]; !ok {
+		return nil, NewSyntaxError(typeToken, fmt.Sprintf("unknown type %v", typeToken.Value))
+	}
+	args := []Expr{expr, Token{
+		Type:   TokenString,
+		Value:  typeName,
+		Pos:    typeToken.Pos,
+		EndPos: typeToken.EndPos,
+	}}
+
+	if p.current.Type == TokenLParen {
+		modifiers, err := p.ParseArguments()
+		if err != nil {
+			return nil, err
+		}
+		if len(modifiers) == 0 || len(modifiers) > 2 {
+			return nil, NewSyntaxError(typeToken, fmt.Sprintf("type %v expects precision and optional scale", typeName))
+		}
+		for _, modifier := range modifiers {
+			if token, isToken := modifier.(Token); !isToken || token.Type != TokenNumber {
+				return nil, NewSyntaxError(typeToken, fmt.Sprintf("precision and scale of %v must be numbers", typeName))
+			}
+		}
+		args = append(args, modifiers...)
+	}
+
+	if _, err := p.Expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// Handle parse EXTRACT(part FROM expr), part becomes string argument
+func (p *Parser) ParseExtractArguments() ([]Expr, error) {
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	partToken := p.current
+	if partToken.Type != TokenIdent && partToken.Type != TokenString {
+		return nil, UnexpectedTokenError(partToken, TokenIdent)
+	}
+	p.Advance()
+	if _, err := p.Expect(TokenFrom); err != nil {
+		return nil, err
+	}
+	expr, err := p.ParseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	part := Token{
+		Type:   TokenString,
+		Value:  strings.ToLower(Stringify(partToken.Value)),
+		Pos:    partToken.Pos,
+		EndPos: partToken.EndPos,
+	}
+	return []Expr{part, expr}, nil
+}
+
+// First non-NULL argument
+func Coalesce(args []Value) (Value, error) {
+	for _, arg := range args {
+		if !arg.IsNull() {
+			return arg, nil
+		}
+	}
+	return NullValue(), nil
+}
+
+// NULL when both arguments are equal, otherwise the first argument
+func NullIf(args []Value) (Value, error) {
+	cmp, isComparable := CompareValues(args[0], args[1])
+	if isComparable && cmp == 0 {
+		return NullValue(), nil
+	}
+	return args[0], nil
+}
+
+// Largest non-NULL argument
+func Greatest(args []Value) (Value, error) {
+	return PickValue(args, 1), nil
+}
+
+// Smallest non-NULL argument
+func Least(args []Value) (Value, error) {
+	return PickValue(args, -1), nil
+}
+
+func PickValue(args []Value, sign int) Value {
+	result := NullValue()
+	for _, arg := range args {
+		if arg.IsNull() {
+			continue
+		}
+		cmp, isComparable := CompareValues(arg, result)
+		if !isComparable || cmp*sign > 0 {
+			result = arg
+		}
+	}
+	return result
+}
+
+// Target kind of CAST by type name
+var castTypes = map[string]ValueKind{
+	"INT":       KindInt,
+	"INTEGER":   KindInt,
+	"BIGINT":    KindInt,
+	"FLOAT":     KindFloat,
+	"DOUBLE":    KindFloat,
+	"REAL":      KindFloat,
+	"DECIMAL":   KindDecimal,
+	"NUMERIC":   KindDecimal,
+	"TEXT":      KindString,
+	"VARCHAR":   KindString,
+	"STRING":    KindString,
+	"DATE":      KindDate,
+	"TIMESTAMP": KindTimestamp,
+	"DATETIME":  KindTimestamp,
+	"BOOL":      KindBool,
+	"BOOLEAN":   KindBool,
+}
+
+// Max digits after decimal point kept when casting float to DECIMAL without scale
+const CastDecimalScale = 15
+
+// CAST(value AS type [(precision [, scale])]), precision is only checked for syntax
+func Cast(args []Value) (Value, error) {
+	typeName := args[1].Str
+	kind, ok := castTypes[strings.ToUpper(typeName)]
+	if !ok {
+		return NullValue(), fmt.Errorf("unknown type %v", typeName)
+	}
+	value := args[0]
+	if value.Kind == KindString && kind != KindString {
+		value = ParseValue(value.Str)
+	}
+	if kind != KindString {
+		value = value.Typed()
+	}
+	result, ok := CastValue(value, kind)
+	if !ok {
+		return NullValue(), fmt.Errorf("cannot cast %v '%v' to %v", args[0].Kind, args[0].String(), typeName)
+	}
+	if kind == KindDecimal && len(args) > 2 {
+		scale := 0
+		if len(args) == 4 {
+			scale = int(args[3].Int)
+		}
+		result = DecimalValue(RoundRat(result.AsRat(), scale), scale)
+	}
+	return result, nil
+}
+
+func CastValue(value Value, kind ValueKind) (Value, bool) {
+	switch kind {
+	case KindString:
+		return StringValue(value.String()), true
+	case KindInt:
+		if value.Kind == KindBool {
+			return IntValue(int64(BooleanToInt(value.Bool))), true
+		}
+		if !value.IsNumeric() {
+			return value, false
+		}
+		return ToInteger(RoundValueOrSelf(value))
+	case KindFloat:
+		if !value.IsNumeric() && value.Kind != KindBool {
+			return value, false
+		}
+		return FloatValue(value.AsFloat()), true
+	case KindDecimal:
+		switch value.Kind {
+		case KindInt, KindDecimal:
+			return DecimalValue(value.AsRat(), value.Scale), true
+		case KindFloat:
+			rat := value.AsRat()
+			return DecimalValue(rat, FitScale(rat, 0, CastDecimalScale)), true
+		}
+	case KindDate:
+		if value.IsTemporal() {
+			year, month, day := value.Time.Date()
+			return DateValue(time.Date(year, month, day, 0, 0, 0, 0, value.Time.Location())), true
+		}
+	case KindTimestamp:
+		if value.IsTemporal() {
+			return TimestampValue(value.Time), true
+		}
+	case KindBool:
+		if value.Kind == KindBool {
+			return value, true
+		}
+		if value.IsNumeric() {
+			return BoolValue(value.AsFloat() != 0), true
+		}
+	}
+	return value, false
+}
+
+// Round number to whole number, keep other values as is
+func RoundValueOrSelf(value Value) Value {
+	rounded, err := RoundValue(value, 0, RoundRat)
+	if err != nil {
+		return value
+	}
+	return rounded
+}
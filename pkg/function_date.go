@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"slices"
+	"time"
+)
+
+// Units accepted by DATE_TRUNC
+var truncUnits = []string{"year", "quarter", "month", "week", "day", "hour", "minute", "second"}
+
+// Fields accepted by EXTRACT and DATE_PART
+var dateParts = []string{"year", "quarter", "month", "week", "day", "dow", "doy", "hour", "minute", "second", "epoch"}
+
+func IsDateUnit(argType ArgType, unit string) bool {
+	if argType == ArgTruncUnit {
+		return slices.Contains(truncUnits, unit)
+	}
+	return slices.Contains(dateParts, unit)
+}
+
+// Current local time as timestamp, without time zone like timestamps read from CSV
+func Now(args []Value) (Value, error) {
+	now := time.Now()
+	return TimestampValue(time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, time.UTC)), nil
+}
+
+func CurrentDate(args []Value) (Value, error) {
+	now := time.Now()
+	return DateValue(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)), nil
+}
+
+// DATE_TRUNC(unit, value): start of the unit containing value, weeks start on Monday
+func DateTrunc(args []Value) (Value, error) {
+	t := args[1].Time
+	year, month, day := t.Date()
+	switch args[0].Str {
+	case "year":
+		t = time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	case "quarter":
+		t = time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, t.Location())
+	case "month":
+		t = time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case "week":
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		t = time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	case "day":
+		t = time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	case "hour":
+		t = time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case "minute":
+		t = time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, t.Location())
+	case "second":
+		t = time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	}
+	return Value{Kind: args[1].Kind, Time: t}, nil
+}
+
+// DATE_PART(part, value) and EXTRACT(part FROM value). Day of week counts from Sunday = 0, week is ISO week
+func DatePart(args []Value) (Value, error) {
+	t := args[1].Time
+	part := 0
+	switch args[0].Str {
+	case "year":
+		part = t.Year()
+	case "quarter":
+		part = (int(t.Month())-1)/3 + 1
+	case "month":
+		part = int(t.Month())
+	case "week":
+		_, part = t.ISOWeek()
+	case "day":
+		part = t.Day()
+	case "dow":
+		part = int(t.Weekday())
+	case "doy":
+		part = t.YearDay()
+	case "hour":
+		part = t.Hour()
+	case "minute":
+		part = t.Minute()
+	case "second":
+		part = t.Second()
+	case "epoch":
+		return IntValue(t.Unix()), nil
+	}
+	return IntValue(int64(part)), nil
+}
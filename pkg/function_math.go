@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+func Abs(args []Value) (Value, error) {
+	value := args[0]
+	isNegative := value.AsRat().Sign() < 0
+	if value.Kind == KindFloat {
+		isNegative = value.Float < 0
+	}
+	if isNegative {
+		return NegateValue(value)
+	}
+	return value, nil
+}
+
+// ROUND(x [, digits]), half away from zero. Negative digits round to tens, hundreds, ...
+func Round(args []Value) (Value, error) {
+	digits := int64(0)
+	if len(args) == 2 {
+		digits = args[1].Int
+	}
+	return RoundValue(args[0], int(digits), RoundRat)
+}
+
+func Floor(args []Value) (Value, error) {
+	return RoundValue(args[0], 0, FloorRat)
+}
+
+func Ceil(args []Value) (Value, error) {
+	return RoundValue(args[0], 0, CeilRat)
+}
+
+// Round number keeping its kind, digits is number of digits kept after decimal point
+func RoundValue(value Value, digits int, round func(rat *big.Rat, scale int) *big.Rat) (Value, error) {
+	if value.Kind == KindFloat {
+		if math.IsInf(value.Float, 0) || math.IsNaN(value.Float) {
+			return value, nil
+		}
+		rounded, _ := round(value.AsRat(), digits).Float64()
+		return FloatValue(rounded), nil
+	}
+	if value.Kind == KindInt && digits >= 0 {
+		return value, nil
+	}
+
+	rounded := round(value.AsRat(), digits)
+	if value.Kind == KindInt {
+		return IntegerValue(rounded)
+	}
+	return DecimalValue(rounded, max(min(digits, value.Scale), 0)), nil
+}
+
+// Exact integer value of rational number, decimal when it doesn't fit int64
+func IntegerValue(rat *big.Rat) (Value, error) {
+	if rat.IsInt() && rat.Num().IsInt64() {
+		return IntValue(rat.Num().Int64()), nil
+	}
+	return DecimalValue(rat, 0), nil
+}
+
+// Round towards negative infinity to given number of digits after decimal point
+func FloorRat(rat *big.Rat, scale int) *big.Rat {
+	return RoundRatFunc(rat, scale, func(quotient *big.Int, isExact bool) {
+		if !isExact && rat.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		}
+	})
+}
+
+// Round towards positive infinity to given number of digits after decimal point
+func CeilRat(rat *big.Rat, scale int) *big.Rat {
+	return RoundRatFunc(rat, scale, func(quotient *big.Int, isExact bool) {
+		if !isExact && rat.Sign() > 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	})
+}
+
+// Truncate rat scaled by 10^scale then let adjust fix the truncated quotient
+func RoundRatFunc(rat *big.Rat, scale int, adjust func(quotient *big.Int, isExact bool)) *big.Rat {
+	factor := ScaleFactor(scale)
+	scaled := new(big.Rat).Mul(rat, factor)
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	adjust(quotient, remainder.Sign() == 0)
+	return new(big.Rat).Quo(new(big.Rat).SetInt(quotient), factor)
+}
+
+func Sign(args []Value) (Value, error) {
+	if args[0].Kind == KindFloat {
+		return IntValue(int64(CompareNumber(args[0].Float, 0))), nil
+	}
+	return IntValue(int64(args[0].AsRat().Sign())), nil
+}
+
+func Mod(args []Value) (Value, error) {
+	return ModuloValues(args[0], args[1])
+}
+
+func Power(args []Value) (Value, error) {
+	result := math.Pow(args[0].AsFloat(), args[1].AsFloat())
+	if math.IsNaN(result) {
+		return NullValue(), fmt.Errorf("POWER of negative number to fractional exponent is undefined")
+	}
+	return FloatValue(result), nil
+}
+
+func Sqrt(args []Value) (Value, error) {
+	if args[0].AsFloat() < 0 {
+		return NullValue(), fmt.Errorf("SQRT of negative number is undefined")
+	}
+	return FloatValue(math.Sqrt(args[0].AsFloat())), nil
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func Upper(args []Value) (Value, error) {
+	return StringValue(strings.ToUpper(args[0].Str)), nil
+}
+
+func Lower(args []Value) (Value, error) {
+	return StringValue(strings.ToLower(args[0].Str)), nil
+}
+
+// Trim whitespace, or any of given characters, from both ends
+func Trim(args []Value) (Value, error) {
+	if len(args) == 1 {
+		return StringValue(strings.TrimSpace(args[0].Str)), nil
+	}
+	return StringValue(strings.Trim(args[0].Str, args[1].Str)), nil
+}
+
+func LTrim(args []Value) (Value, error) {
+	if len(args) == 1 {
+		return StringValue(strings.TrimLeftFunc(args[0].Str, unicode.IsSpace)), nil
+	}
+	return StringValue(strings.TrimLeft(args[0].Str, args[1].Str)), nil
+}
+
+func RTrim(args []Value) (Value, error) {
+	if len(args) == 1 {
+		return StringValue(strings.TrimRightFunc(args[0].Str, unicode.IsSpace)), nil
+	}
+	return StringValue(strings.TrimRight(args[0].Str, args[1].Str)), nil
+}
+
+// Number of characters, not bytes
+func Length(args []Value) (Value, error) {
+	return IntValue(int64(utf8.RuneCountInString(args[0].Str))), nil
+}
+
+// SUBSTR(str, start [, length]) with 1-based start. Positions outside of string are clipped
+func Substr(args []Value) (Value, error) {
+	chars := []rune(args[0].Str)
+	start := args[1].Int
+	end := int64(len(chars)) + 1
+	if len(args) == 3 {
+		if args[2].Int < 0 {
+			return NullValue(), fmt.Errorf("negative substring length not allowed")
+		}
+		end = min(end, start+args[2].Int)
+	}
+	start = max(start, 1)
+	if start >= end {
+		return StringValue(""), nil
+	}
+	return StringValue(string(chars[start-1 : end-1])), nil
+}
+
+func Replace(args []Value) (Value, error) {
+	if len(args[1].Str) == 0 {
+		return args[0], nil
+	}
+	return StringValue(strings.ReplaceAll(args[0].Str, args[1].Str, args[2].Str)), nil
+}
+
+// SPLIT_PART(str, delimiter, n): n-th field, counting from the end when negative
+func SplitPart(args []Value) (Value, error) {
+	n := args[2].Int
+	if n == 0 {
+		return NullValue(), fmt.Errorf("field position must not be zero")
+	}
+	parts := []string{args[0].Str}
+	if len(args[1].Str) > 0 {
+		parts = strings.Split(args[0].Str, args[1].Str)
+	}
+	if n < 0 {
+		n += int64(len(parts)) + 1
+	}
+	if n < 1 || n > int64(len(parts)) {
+		return StringValue(""), nil
+	}
+	return StringValue(parts[n-1]), nil
+}
+
+// 1-based character position of substring, 0 when not found
+func StrPos(args []Value) (Value, error) {
+	idx := strings.Index(args[0].Str, args[1].Str)
+	if idx < 0 {
+		return IntValue(0), nil
+	}
+	return IntValue(int64(utf8.RuneCountInString(args[0].Str[:idx])) + 1), nil
+}
+
+func Reverse(args []Value) (Value, error) {
+	chars := []rune(args[0].Str)
+	slices.Reverse(chars)
+	return StringValue(string(chars)), nil
+}
+
+func LPad(args []Value) (Value, error) {
+	return Pad(args, true)
+}
+
+func RPad(args []Value) (Value, error) {
+	return Pad(args, false)
+}
+
+// Fill string up to length with fill characters (space by default), longer string is truncated
+func Pad(args []Value, isLeft bool) (Value, error) {
+	chars := []rune(args[0].Str)
+	length := int(max(args[1].Int, 0))
+	fill := []rune(" ")
+	if len(args) == 3 {
+		fill = []rune(args[2].Str)
+	}
+	if len(chars) >= length {
+		return StringValue(string(chars[:length])), nil
+	}
+	if len(fill) == 0 {
+		return args[0], nil
+	}
+	padding := []rune{}
+	for len(padding) < length-len(chars) {
+		padding = append(padding, fill[len(padding)%len(fill)])
+	}
+	if isLeft {
+		return StringValue(string(padding) + string(chars)), nil
+	}
+	return StringValue(string(chars) + string(padding)), nil
+}
+
+// Join arguments as text, NULL arguments are skipped
+func Concat(args []Value) (Value, error) {
+	var builder strings.Builder
+	for _, arg := range args {
+		builder.WriteString(arg.String())
+	}
+	return StringValue(builder.String()), nil
+}
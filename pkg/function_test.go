@@ -0,0 +1,103 @@
+package pkg
+
+import "testing"
+
+func TestExecuteScalarFunctions(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"UPPER('abc')", "ABC"},
+		{"LOWER(name)", "ann"},
+		{"TRIM('  a b  ')", "a b"},
+		{"TRIM('xxaxx', 'x')", "a"},
+		{"LTRIM('  a ')", "a "},
+		{"RTRIM('a..', '.')", "a"},
+		{"LENGTH('héllo')", "5"},
+		{"LENGTH(NULL)", ""},
+		{"SUBSTR('abcdef', 2, 3)", "bcd"},
+		{"SUBSTR('abcdef', 4)", "def"},
+		{"SUBSTR('abcdef', 0, 2)", "a"},
+		{"SUBSTRING('héllo', 2, 1)", "é"},
+		{"REPLACE('a-b-c', '-', '+')", "a+b+c"},
+		{"SPLIT_PART('a,b,c', ',', 2)", "b"},
+		{"SPLIT_PART('a,b,c', ',', 5)", ""},
+		{"STRPOS('hello', 'l')", "3"},
+		{"REVERSE('abc')", "cba"},
+		{"LPAD('7', 3, '0')", "007"},
+		{"RPAD('ab', 5)", "ab   "},
+		{"LPAD('abcdef', 3)", "abc"},
+		{"CONCAT('a', NULL, 1)", "a1"},
+		{"ROUND(2.345, 2)", "2.35"},
+		{"ROUND(-2.5)", "-3"},
+		{"ROUND(1234, -2)", "1200"},
+		{"ABS(-1.50)", "1.50"},
+		{"FLOOR(-1.5)", "-2"},
+		{"CEIL(1.2)", "2"},
+		{"SIGN(-4.2)", "-1"},
+		{"MOD(-7, 3)", "-1"},
+		{"POWER(2, 10)", "1024"},
+		{"SQRT(2)", "1.4142135623730951"},
+		{"DATE_TRUNC('month', '2024-03-15')", "2024-03-01"},
+		{"DATE_TRUNC('hour', '2024-03-15 10:45:00')", "2024-03-15 10:00:00"},
+		{"DATE_PART('year', '2024-03-15')", "2024"},
+		{"EXTRACT(MONTH FROM '2024-03-15')", "3"},
+		{"EXTRACT(dow FROM '2024-03-17')", "0"},
+		{"CAST('42' AS INT) + 1", "43"},
+		{"CAST(3.7 AS INTEGER)", "4"},
+		{"CAST('1.50' AS DECIMAL)", "1.50"},
+		{"CAST(5 AS TEXT) || 'x'", "5x"},
+		{"COALESCE(NULL, NULL, 'z')", "z"},
+		{"IFNULL(NULL, 2)", "2"},
+		{"NULLIF(1, 1)", ""},
+		{"NULLIF(1, 2)", "1"},
+		{"GREATEST(3, 10, 2)", "10"},
+		{"LEAST('b', 'a')", "a"},
+		{"GREATEST(1, NULL)", "1"},
+		// Function names are case-insensitive and calls nest in any expression
+		{"upper(name) || lower('X')", "ANNx"},
+		{"LENGTH(UPPER(name))", "3"},
+		{"ROUND(AVG(id), 1)", "1"},
+	}
+	for _, test := range tests {
+		sql := "SELECT " + test.expr + " FROM customers WHERE id = 1"
+		if got := executeQuery(t, sql); len(got) != 2 || got[1][0] != test.want {
+			t.Errorf("%v = %q, want %q", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestScalarFunctionErrors(t *testing.T) {
+	// Arity, literal argument types and unknown names are reported at parse time
+	tests := []struct {
+		expr string
+		pos  int
+		want string
+	}{
+		{"UPPER()", 7, "function UPPER expects 1 argument, got 0"},
+		{"UPPER('a', 'b')", 7, "function UPPER expects 1 argument, got 2"},
+		{"SUBSTR('a')", 7, "function SUBSTR expects 2 to 3 arguments, got 1"},
+		{"NOW(1)", 7, "function NOW expects 0 arguments, got 1"},
+		{"ROUND('x')", 13, "function ROUND expects number as argument 1, got 'x'"},
+		{"LPAD('a', 'b')", 17, "function LPAD expects integer as argument 2, got 'b'"},
+		{"DATE_TRUNC('week2', '2024-01-01')", 18, "function DATE_TRUNC expects date unit as argument 1, got 'week2'"},
+		{"EXTRACT(fortnight FROM '2024-01-01')", 15, "function EXTRACT expects date part as argument 1, got 'fortnight'"},
+		{"DATE_PART('year', 'abc')", 25, "function DATE_PART expects date or timestamp as argument 2, got 'abc'"},
+		{"NOSUCH(1)", 7, "unknown function NOSUCH"},
+		{"CAST(1 AS BLOB)", 17, "unknown type BLOB"},
+		{"CAST('abc' AS INT)", 7, "cannot cast string 'abc' to INT"},
+		{"SQRT(-1)", 7, "SQRT of negative number is undefined"},
+	}
+	for _, test := range tests {
+		sql := "SELECT " + test.expr + " FROM customers WHERE id = 1"
+		if err := syntaxError(t, sql); err.Message != test.want || err.Pos != test.pos {
+			t.Errorf("%v: got %q at %d, want %q at %d", test.expr, err.Message, err.Pos, test.want, test.pos)
+		}
+	}
+
+	// Column arguments are only known per row
+	want := "function ABS expects number as argument 1, got 'Ann'"
+	if err := executeError(t, "SELECT ABS(name) FROM customers"); err.Error() != want {
+		t.Errorf("ABS(name): got %v, want %q", err, want)
+	}
+}
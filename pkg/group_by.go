@@ -1,100 +1,554 @@
 package pkg
 
 import (
+	"fmt"
+	"math"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// GROUP BY pkg
-func ProcessGroupByPerRow(row []string, headerRow []string, headerIndex map[string]int, tokens []Token) ([]string, []string, []string, []string, string) {
-	keyArr := []string{"groupBy"}
-	fieldIndexes := []int{}
-	groupByFields := []string{}
-	groupByData := []string{}
-	otherFields := []string{}
-	otherData := []string{}
-	for _, token := range tokens {
-		headerIdx := headerIndex[Stringify(token.Value)]
-		fieldIndexes = append(fieldIndexes, headerIdx)
-	}
-	for i, val := range row {
-		if !slices.Contains(fieldIndexes, i) {
-			otherData = append(otherData, val)
-			otherFields = append(otherFields, headerRow[i])
-			continue
+// Running state of one group: its first row, used for GROUP BY columns, and one accumulator per aggregate call
+type Group struct {
+	Row          []string
+	Set          int           // Index of grouping set of group
+	Accumulators []Accumulator // Aligned with HashAggregate.Aggregates
+}
+
+// Hash aggregation of GROUP BY. Groups are keyed by grouping set and typed GROUP BY values and kept in
+// first-seen order within each grouping set. Plain GROUP BY has one grouping set of all its columns
+type HashAggregate struct {
+	GroupBy      []Expr
+	GroupingSets [][]Expr
+	Aggregates   []AggregateExpr // Distinct aggregate calls of select columns, HAVING and ORDER BY
+	Groupings    []GroupingExpr  // Distinct GROUPING calls of select columns, HAVING and ORDER BY
+	Groups       map[string]*Group
+	Order        [][]*Group // Groups of each grouping set
+}
+
+func NewHashAggregate(groupBy []Expr, groupingSets [][]Expr, exprs []interface{}) *HashAggregate {
+	if groupingSets == nil {
+		groupingSets = [][]Expr{groupBy}
+	}
+	aggregates := []AggregateExpr{}
+	groupings := []GroupingExpr{}
+	seen := map[string]bool{}
+	for _, expr := range exprs {
+		for _, aggregate := range CollectAggregates(expr) {
+			key := AggregateKey(aggregate)
+			if !seen[key] {
+				seen[key] = true
+				aggregates = append(aggregates, aggregate)
+			}
+		}
+		for _, grouping := range CollectGroupings(expr) {
+			key := GroupingKey(grouping)
+			if !seen[key] {
+				seen[key] = true
+				groupings = append(groupings, grouping)
+			}
+		}
+	}
+	return &HashAggregate{
+		GroupBy:      groupBy,
+		GroupingSets: groupingSets,
+		Aggregates:   aggregates,
+		Groupings:    groupings,
+		Groups:       map[string]*Group{},
+		Order:        make([][]*Group, len(groupingSets)),
+	}
+}
+
+// Whether query aggregates rows: it has GROUP BY, HAVING or aggregate in select columns
+func IsAggregateQuery(ast AST) bool {
+	if ast.GroupBy != nil || ast.Having != nil {
+		return true
+	}
+	for _, col := range ast.Columns {
+		if ContainsAggregate(col.Expr) {
+			return true
 		}
-		keyArr = append(keyArr, val)
-		groupByFields = append(groupByFields, headerRow[i])
-		groupByData = append(groupByData, val)
 	}
-	return groupByFields, groupByData, otherFields, otherData, strings.Join(keyArr, "_")
+	return false
+}
+
+// Expressions of query whose aggregate calls are computed per group
+func AggregateSources(ast AST) []interface{} {
+	exprs := []interface{}{}
+	for _, col := range ast.Columns {
+		exprs = append(exprs, col.Expr)
+	}
+	exprs = append(exprs, ast.Having)
+	for _, condition := range ast.OrderBy {
+		exprs = append(exprs, condition.Expr)
+	}
+	return exprs
+}
+
+// Encode values into collision-free key for GROUP BY, DISTINCT and PARTITION BY. Each part is tagged and
+// prefixed with its length. Values are keyed by their text, so 00501 and 501 or 1e3 and 1000 stay apart,
+// NULLs group together
+func GroupKey(values []Value) string {
+	return EncodeKey(values, TextKeyPart)
+}
+
+// Encode typed values into collision-free key, numbers are keyed by exact value so 1 and 1.0 are equal like with =
+func TypedKey(values []Value) string {
+	return EncodeKey(values, TypedKeyPart)
+}
+
+func EncodeKey(values []Value, keyPart func(Value) (byte, string)) string {
+	var builder strings.Builder
+	for _, value := range values {
+		tag, text := keyPart(value)
+		fmt.Fprintf(&builder, "%c%d:%v", tag, len(text), text)
+	}
+	return builder.String()
+}
+
+func TextKeyPart(value Value) (byte, string) {
+	if value.IsNull() {
+		return 'N', ""
+	}
+	return 's', value.String()
 }
 
-func AppendGroupByData(groupByMap map[string]GroupByData, key string, otherFields []string, otherData []string) bool {
-	_, ok := groupByMap[key]
-	if !ok {
-		groupByMap[key] = map[string]Collector{}
+func TypedKeyPart(value Value) (byte, string) {
+	switch {
+	case value.IsNull():
+		return 'N', ""
+	case value.Kind == KindFloat && (math.IsInf(value.Float, 0) || math.IsNaN(value.Float)):
+		return 'f', strconv.FormatFloat(value.Float, 'g', -1, 64)
+	case value.IsNumeric():
+		return 'n', value.AsRat().RatString()
+	case value.Kind == KindBool:
+		return 'b', strconv.FormatBool(value.Bool)
+	case value.IsTemporal():
+		return 't', value.Time.Format(time.RFC3339Nano)
+	default:
+		return 's', value.Str
 	}
-	isGrouped := false
-	for i, field := range otherFields {
-		_, ok := groupByMap[key][field]
+}
+
+// Add row into its group of every grouping set, creating the group on first sight
+func (agg *HashAggregate) Add(row []string, headerIndex map[string]int) error {
+	for set, exprs := range agg.GroupingSets {
+		values := []Value{IntValue(int64(set))}
+		for _, expr := range exprs {
+			value, err := Eval(expr, row, headerIndex)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		key := GroupKey(values)
+
+		group, ok := agg.Groups[key]
 		if !ok {
-			groupByMap[key][field] = Collector{otherData[i]}
-		} else {
-			groupByMap[key][field] = append(groupByMap[key][field], otherData[i])
-			isGrouped = true
+			var err error
+			group, err = agg.NewGroup(key, set, agg.GroupingRow(set, row, headerIndex))
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, accumulator := range group.Accumulators {
+			if err := AccumulateRow(accumulator, agg.Aggregates[i], row, headerIndex); err != nil {
+				return err
+			}
 		}
 	}
-	return isGrouped
+	return nil
 }
 
-func BuildGroupByRow(groupByFields []string, groupByData []string, headerRow []string, key string) []string {
-	row := []string{}
-	groupByPointer := 0
-	for _, val := range headerRow {
-		if !slices.Contains(groupByFields, val) {
-			row = append(row, key)
-		} else {
-			row = append(row, groupByData[groupByPointer])
-			groupByPointer++
+// Copy of row whose columns of GROUP BY expressions outside of grouping set are NULL, as in subtotal rows
+// of ROLLUP. Columns also used by expressions of grouping set are kept
+func (agg *HashAggregate) GroupingRow(set int, row []string, headerIndex map[string]int) []string {
+	if len(agg.GroupingSets[set]) == len(agg.GroupBy) {
+		return row
+	}
+	grouped := []string{}
+	for _, expr := range agg.GroupingSets[set] {
+		grouped = append(grouped, ExprColumns(expr)...)
+	}
+	groupingRow := slices.Clone(row)
+	for _, expr := range agg.GroupBy {
+		for _, name := range ExprColumns(expr) {
+			if idx, ok := headerIndex[name]; ok && !slices.Contains(grouped, name) {
+				groupingRow[idx] = NullCell
+			}
 		}
 	}
+	return groupingRow
+}
+
+// Create group of grouping set with fresh accumulators
+func (agg *HashAggregate) NewGroup(key string, set int, row []string) (*Group, error) {
+	group := &Group{
+		Row:          row,
+		Set:          set,
+		Accumulators: []Accumulator{},
+	}
+	for _, aggregate := range agg.Aggregates {
+		accumulator, err := NewAccumulator(aggregate)
+		if err != nil {
+			return nil, err
+		}
+		group.Accumulators = append(group.Accumulators, accumulator)
+	}
+	agg.Groups[key] = group
+	agg.Order[set] = append(agg.Order[set], group)
+	return group, nil
+}
+
+// Empty grouping set aggregates whole table, which yields one group even when there is no row
+func (agg *HashAggregate) EnsureWholeTableGroup() error {
+	for set, columns := range agg.GroupingSets {
+		if len(columns) > 0 || len(agg.Order[set]) > 0 {
+			continue
+		}
+		if _, err := agg.NewGroup(GroupKey([]Value{IntValue(int64(set))}), set, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Groups of all grouping sets, in order of grouping sets
+func (agg *HashAggregate) AllGroups() []*Group {
+	groups := []*Group{}
+	for _, setGroups := range agg.Order {
+		groups = append(groups, setGroups...)
+	}
+	return groups
+}
+
+// Row of group: first row of group padded with NULLs to width, followed by aggregate results and GROUPING results
+func (agg *HashAggregate) GroupRow(group *Group, width int) []string {
+	row := NullRow(width)
+	copy(row, group.Row)
+	for _, accumulator := range group.Accumulators {
+		row = append(row, accumulator.Result().Cell())
+	}
+	for _, grouping := range agg.Groupings {
+		row = append(row, GroupingValue(grouping, agg.GroupingSets[group.Set]).Cell())
+	}
 	return row
 }
 
-func GetAggregateCollector(groupByMap map[string]GroupByData, groupByKey, field string) []string {
-	collector, ok := groupByMap[groupByKey][field]
-	if !ok {
-		return []string{}
+// Width of group row
+func (agg *HashAggregate) GroupRowWidth(width int) int {
+	return width + len(agg.Aggregates) + len(agg.Groupings)
+}
+
+// Header index of group row, aggregate results are named by AggregateKey and GROUPING results by GroupingKey
+func (agg *HashAggregate) GroupHeaderIndex(headerIndex map[string]int, width int) map[string]int {
+	groupHeaderIndex := map[string]int{}
+	for name, idx := range headerIndex {
+		groupHeaderIndex[name] = idx
+	}
+	for i, aggregate := range agg.Aggregates {
+		groupHeaderIndex[AggregateKey(aggregate)] = width + i
 	}
-	return collector
+	for i, grouping := range agg.Groupings {
+		groupHeaderIndex[GroupingKey(grouping)] = width + len(agg.Aggregates) + i
+	}
+	return groupHeaderIndex
 }
 
-func GetAggregateValue(token Column, collector []string) string {
-	switch token.Type {
-	case TokenSum:
-		{
-			return Sum(collector)
+// Header index for HAVING over group row followed by output row, so HAVING can refer to output
+// column names. Source columns take precedence over output names
+func HavingHeaderIndex(groupHeaderIndex map[string]int, columns []Column, offset int) map[string]int {
+	havingIndex := map[string]int{}
+	for name, idx := range groupHeaderIndex {
+		havingIndex[name] = idx
+	}
+	for i, col := range columns {
+		name := ColumnName(col)
+		if _, exists := havingIndex[name]; !exists {
+			havingIndex[name] = offset + i
 		}
-	case TokenCount:
-		{
-			return Count(collector)
+	}
+	return havingIndex
+}
+
+// Resolve aliases of select columns in GROUP BY and validate grouped columns, HAVING and GROUPING calls.
+// Name of source column takes precedence over alias, so this runs after scan when header is known
+func ResolveGroupBy(ast AST, header []string) (AST, error) {
+	if ast.GroupBy != nil {
+		sets := ast.GroupingSets
+		if sets == nil {
+			sets = [][]Expr{ast.GroupBy}
 		}
-	case TokenAverage:
-		{
-			return Average(collector)
+		resolvedSets := [][]Expr{}
+		for _, set := range sets {
+			resolvedSet := []Expr{}
+			for _, expr := range set {
+				expr = ResolveGroupByAlias(expr, ast.Columns, header)
+				if err := ValidateGroupByExpr(expr); err != nil {
+					return ast, err
+				}
+				resolvedSet = append(resolvedSet, expr)
+			}
+			resolvedSets = append(resolvedSets, resolvedSet)
 		}
-	case TokenMax:
-		{
-			return Max(collector)
+		ast.GroupBy = GroupingSetsExprs(resolvedSets)
+		if ast.GroupingSets != nil {
+			ast.GroupingSets = resolvedSets
 		}
-	case TokenMin:
-		{
-			return Min(collector)
+	}
+
+	// Whole table is one group of aggregate query without GROUP BY
+	if IsAggregateQuery(ast) {
+		if err := ValidateGroupByColumns(ast.Columns, ast.GroupBy); err != nil {
+			return ast, err
 		}
-	default:
-		{
-			panic("Syntax error")
+		if err := ValidateHavingColumns(ast.Having, NonWindowColumns(ast.Columns), ast.GroupBy); err != nil {
+			return ast, err
+		}
+	}
+	if err := ValidateGroupingArgs(AggregateSources(ast), ast.GroupBy); err != nil {
+		return ast, err
+	}
+	return ast, nil
+}
+
+// Expression of select column when GROUP BY name is its alias and no source column has that name
+func ResolveGroupByAlias(expr Expr, columns []Column, header []string) Expr {
+	token, isToken := expr.(Token)
+	if !isToken || token.Type != TokenIdent || slices.Contains(header, Stringify(token.Value)) {
+		return expr
+	}
+	for _, col := range columns {
+		if col.Type != TokenStar && col.Alias == Stringify(token.Value) {
+			return col.Expr
+		}
+	}
+	return expr
+}
+
+// Distinct expressions of all grouping sets, in order of first use
+func GroupingSetsExprs(groupingSets [][]Expr) []Expr {
+	groupBy := []Expr{}
+	for _, set := range groupingSets {
+		for _, expr := range set {
+			if !HasGroupingExpr(groupBy, expr) {
+				groupBy = append(groupBy, expr)
+			}
+		}
+	}
+	return groupBy
+}
+
+// Check that GROUP BY expression has no aggregate, window function or GROUPING call
+func ValidateGroupByExpr(expr Expr) error {
+	if aggregates := CollectAggregates(expr); len(aggregates) > 0 {
+		return NewSyntaxError(aggregates[0].Name, "aggregate functions are not allowed in GROUP BY")
+	}
+	if windows := CollectWindows(expr); len(windows) > 0 {
+		return NewSyntaxError(windows[0].Name, "window functions are not allowed in GROUP BY")
+	}
+	if groupings := CollectGroupings(expr); len(groupings) > 0 {
+		return NewSyntaxError(groupings[0].Name, "GROUPING is not allowed in GROUP BY")
+	}
+	return nil
+}
+
+// Check that every non-aggregate column only uses GROUP BY expressions
+func ValidateGroupByColumns(columns []Column, groupBy []Expr) error {
+	keys := []string{}
+	for _, expr := range groupBy {
+		keys = append(keys, ExprString(expr))
+	}
+	for _, col := range columns {
+		if col.Type == TokenStar {
+			return NewSyntaxError(col.Token(), "SELECT * can not be used with GROUP BY or aggregate functions")
+		}
+		if names := UngroupedColumns(col.Expr, keys); len(names) > 0 {
+			return NewSyntaxError(col.Token(), fmt.Sprintf(`column "%v" must appear in GROUP BY or be used in an aggregate function`, names[0]))
+		}
+	}
+	return nil
+}
+
+// Check that HAVING only uses GROUP BY expressions and output column names outside of aggregate calls
+func ValidateHavingColumns(having interface{}, columns []Column, groupBy []Expr) error {
+	keys := []string{}
+	for _, expr := range groupBy {
+		keys = append(keys, ExprString(expr))
+	}
+	for _, col := range columns {
+		keys = append(keys, ColumnName(col))
+	}
+	if names := UngroupedColumns(having, keys); len(names) > 0 {
+		return fmt.Errorf(`column "%v" in HAVING must appear in GROUP BY, be an output column or be used in an aggregate function`, names[0])
+	}
+	return nil
+}
+
+// Names of columns referenced by expression outside of grouped expressions, given by their text, and aggregate calls
+func UngroupedColumns(expr interface{}, keys []string) []string {
+	if expr == nil || slices.Contains(keys, ExprString(expr)) {
+		return nil
+	}
+	if _, isAggregate := expr.(AggregateExpr); isAggregate {
+		return nil
+	}
+	if token, isToken := expr.(Token); isToken {
+		return ExprColumns(token)
+	}
+	columns := []string{}
+	for _, child := range ExprChildren(expr) {
+		columns = append(columns, UngroupedColumns(child, keys)...)
+	}
+	return columns
+}
+
+// GROUPING(a, b) call, bit of argument is 1 when the column is not grouped in the row's grouping set
+type GroupingExpr struct {
+	Name Token
+	Args []Expr
+}
+
+// Number of expressions of CUBE, which yields 2^n grouping sets
+const maxCubeColumns = 12
+
+// Handle parse GROUPING call with GROUP BY column arguments
+func (p *Parser) ParseGroupingCall(name Token) (Expr, error) {
+	name.Value = "GROUPING"
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	grouping := GroupingExpr{Name: name}
+	for {
+		arg, err := p.ParseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		grouping.Args = append(grouping.Args, arg)
+		if p.current.Type == TokenRParen {
+			break
+		}
+		if p.current.Type != TokenComma {
+			return nil, UnexpectedTokenError(p.current, TokenComma, TokenRParen)
+		}
+		p.Advance()
+	}
+	p.Advance()
+	if p.current.Type == TokenOver {
+		return nil, NewSyntaxError(p.current, "GROUPING can not be used as window function")
+	}
+	return grouping, nil
+}
+
+func GroupingString(grouping GroupingExpr) string {
+	args := []string{}
+	for _, arg := range grouping.Args {
+		args = append(args, ExprString(arg))
+	}
+	return fmt.Sprintf("GROUPING(%v)", strings.Join(args, ", "))
+}
+
+// Name of GROUPING result column in group row
+func GroupingKey(grouping GroupingExpr) string {
+	return "#" + GroupingString(grouping)
+}
+
+// Bit mask of GROUPING for grouping set, first argument is the most significant bit
+func GroupingValue(grouping GroupingExpr, set []Expr) Value {
+	mask := int64(0)
+	for _, arg := range grouping.Args {
+		mask <<= 1
+		if !HasGroupingExpr(set, arg) {
+			mask |= 1
+		}
+	}
+	return IntValue(mask)
+}
+
+// Get result of GROUPING call from group row
+func ComputeGrouping(grouping GroupingExpr, row []string, headerIndex map[string]int) (Value, error) {
+	fieldIdx, ok := headerIndex[GroupingKey(grouping)]
+	if !ok || fieldIdx >= len(row) {
+		return NullValue(), fmt.Errorf("%v is not allowed here", GroupingString(grouping))
+	}
+	return CellValue(row[fieldIdx]), nil
+}
+
+// GROUPING calls within expression
+func CollectGroupings(expr interface{}) []GroupingExpr {
+	if grouping, isGrouping := expr.(GroupingExpr); isGrouping {
+		return []GroupingExpr{grouping}
+	}
+	groupings := []GroupingExpr{}
+	for _, child := range ExprChildren(expr) {
+		if child != nil {
+			groupings = append(groupings, CollectGroupings(child)...)
+		}
+	}
+	return groupings
+}
+
+// Check that arguments of GROUPING calls are GROUP BY expressions
+func ValidateGroupingArgs(exprs []interface{}, groupBy []Expr) error {
+	for _, expr := range exprs {
+		for _, grouping := range CollectGroupings(expr) {
+			for _, arg := range grouping.Args {
+				if !HasGroupingExpr(groupBy, arg) {
+					return NewSyntaxError(grouping.Name, fmt.Sprintf(`argument %v of GROUPING must be a GROUP BY expression`, ExprString(arg)))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Whether expression is one of grouping expressions, compared by text
+func HasGroupingExpr(exprs []Expr, expr interface{}) bool {
+	text := ExprString(expr)
+	return slices.ContainsFunc(exprs, func(groupingExpr Expr) bool {
+		return ExprString(groupingExpr) == text
+	})
+}
+
+// Grouping sets of ROLLUP(a, b, c): (a, b, c), (a, b), (a) and ()
+func RollupSets(exprs []Expr) [][]Expr {
+	sets := [][]Expr{}
+	for i := len(exprs); i >= 0; i-- {
+		sets = append(sets, slices.Clone(exprs[:i]))
+	}
+	return sets
+}
+
+// Grouping sets of CUBE(a, b): every subset, (a, b), (a), (b) and ()
+func CubeSets(exprs []Expr) [][]Expr {
+	sets := [][]Expr{}
+	for mask := 1<<len(exprs) - 1; mask >= 0; mask-- {
+		set := []Expr{}
+		for i, expr := range exprs {
+			if mask&(1<<(len(exprs)-1-i)) != 0 {
+				set = append(set, expr)
+			}
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// Every union of a left set and a right set, e.g. GROUP BY a, ROLLUP(b) gives (a, b) and (a)
+func CrossGroupingSets(left [][]Expr, right [][]Expr) [][]Expr {
+	sets := [][]Expr{}
+	for _, leftSet := range left {
+		for _, rightSet := range right {
+			set := slices.Clone(leftSet)
+			for _, expr := range rightSet {
+				if !HasGroupingExpr(set, expr) {
+					set = append(set, expr)
+				}
+			}
+			sets = append(sets, set)
 		}
 	}
+	return sets
 }
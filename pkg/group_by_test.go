@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Render grouping sets as "(a, b)" for comparison
+func groupingSetsString(sets [][]Expr) []string {
+	texts := []string{}
+	for _, set := range sets {
+		parts := []string{}
+		for _, expr := range set {
+			parts = append(parts, ExprString(expr))
+		}
+		texts = append(texts, "("+strings.Join(parts, ", ")+")")
+	}
+	return texts
+}
+
+func TestGroupingSets(t *testing.T) {
+	a, b, c := Token{Type: TokenIdent, Value: "a"}, Token{Type: TokenIdent, Value: "b"}, Token{Type: TokenIdent, Value: "c"}
+	tests := []struct {
+		name string
+		sets [][]Expr
+		want []string
+	}{
+		{"ROLLUP(a, b)", RollupSets([]Expr{a, b}), []string{"(a, b)", "(a)", "()"}},
+		{"CUBE(a, b)", CubeSets([]Expr{a, b}), []string{"(a, b)", "(a)", "(b)", "()"}},
+		{"CUBE(a, b, c)", CubeSets([]Expr{a, b, c}), []string{"(a, b, c)", "(a, b)", "(a, c)", "(a)", "(b, c)", "(b)", "(c)", "()"}},
+		{
+			"a, ROLLUP(b)",
+			CrossGroupingSets([][]Expr{{a}}, RollupSets([]Expr{b})),
+			[]string{"(a, b)", "(a)"},
+		},
+		{
+			// Expression already in left set isn't repeated
+			"ROLLUP(a), CUBE(a, b)",
+			CrossGroupingSets(RollupSets([]Expr{a}), CubeSets([]Expr{a, b})),
+			[]string{"(a, b)", "(a)", "(a, b)", "(a)", "(a, b)", "(a)", "(b)", "()"},
+		},
+	}
+	for _, test := range tests {
+		if got := groupingSetsString(test.sets); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExecuteGroupingSets(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			// GROUPING bit is 1 for column rolled up in subtotal row, first argument is highest bit
+			"SELECT region, rep, SUM(amount), GROUPING(region) AS g1, GROUPING(rep) AS g2, GROUPING(region, rep) AS g FROM sales GROUP BY ROLLUP(region, rep)",
+			[][]string{
+				{"region", "rep", "SUM(amount)", "g1", "g2", "g"},
+				{"east", "Ann", "30", "0", "0", "0"},
+				{"east", "Ben", "20", "0", "0", "0"},
+				{"west", "Cid", "35", "0", "0", "0"},
+				{"west", "Dee", "15", "0", "0", "0"},
+				{"north", "Eve", "40", "0", "0", "0"},
+				{"east", "", "50", "0", "1", "1"},
+				{"west", "", "50", "0", "1", "1"},
+				{"north", "", "40", "0", "1", "1"},
+				{"", "", "140", "1", "1", "3"},
+			},
+		},
+		{
+			"SELECT region, rep, COUNT(*), GROUPING(region, rep) AS g FROM sales WHERE region <> 'north' GROUP BY CUBE(region, rep) ORDER BY g, region, rep",
+			[][]string{
+				{"region", "rep", "COUNT(*)", "g"},
+				{"east", "Ann", "2", "0"},
+				{"east", "Ben", "1", "0"},
+				{"west", "Cid", "2", "0"},
+				{"west", "Dee", "1", "0"},
+				{"east", "", "3", "1"},
+				{"west", "", "3", "1"},
+				{"", "Ann", "2", "2"},
+				{"", "Ben", "1", "2"},
+				{"", "Cid", "2", "2"},
+				{"", "Dee", "1", "2"},
+				{"", "", "6", "3"},
+			},
+		},
+		{
+			"SELECT region, rep, COUNT(*) FROM sales WHERE id < 4 GROUP BY GROUPING SETS ((region), (rep), ())",
+			[][]string{{"region", "rep", "COUNT(*)"}, {"east", "", "3"}, {"", "Ann", "2"}, {"", "Ben", "1"}, {"", "", "3"}},
+		},
+		{
+			// Subtotal row is kept apart from group whose value is NULL
+			"SELECT score, GROUPING(score), COUNT(*) FROM sales WHERE region = 'east' GROUP BY ROLLUP(score)",
+			[][]string{{"score", "GROUPING(score)", "COUNT(*)"}, {"1.5", "0", "1"}, {"2", "0", "1"}, {"", "0", "1"}, {"", "1", "3"}},
+		},
+	})
+}
+
+func TestCubeColumnLimit(t *testing.T) {
+	columns := strings.Repeat("id, region, ", maxCubeColumns/2)
+	sql := "SELECT COUNT(*) FROM sales GROUP BY CUBE(" + columns + "rep)"
+	if err := syntaxError(t, sql); err.Message != "CUBE is limited to 12 expressions" || err.Found != TokenCube {
+		t.Errorf("got %v, want CUBE limit error at CUBE", err)
+	}
+
+	// CUBE of exactly maxCubeColumns expressions is allowed
+	ql := CSVQL{Sql: "SELECT COUNT(*) FROM sales GROUP BY CUBE(" + strings.TrimSuffix(columns, ", ") + ")"}
+	ql.Tokenizer()
+	ql.BuildAST()
+	if ql.Error != nil {
+		t.Errorf("CUBE of %d expressions: %v", maxCubeColumns, ql.Error)
+	}
+}
+
+func TestExecuteMultiColumnGroupBy(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			// Groups come out in first-seen order, aggregates skip NULLs within group
+			"SELECT region, rep, COUNT(*), SUM(amount), AVG(score) FROM sales GROUP BY region, rep",
+			[][]string{
+				{"region", "rep", "COUNT(*)", "SUM(amount)", "AVG(score)"},
+				{"east", "Ann", "2", "30", "1.5"},
+				{"east", "Ben", "1", "20", "2"},
+				{"west", "Cid", "2", "35", "1.75"},
+				{"west", "Dee", "1", "15", "1"},
+				{"north", "Eve", "1", "40", "4"},
+			},
+		},
+		{
+			"SELECT rep, region, MAX(id) FROM sales GROUP BY 2, 1 ORDER BY region, rep DESC",
+			[][]string{{"rep", "region", "MAX(id)"}, {"Ben", "east", "2"}, {"Ann", "east", "3"}, {"Eve", "north", "7"}, {"Dee", "west", "5"}, {"Cid", "west", "6"}},
+		},
+		{
+			// Values whose joined text is equal stay separate groups
+			"SELECT CASE WHEN id = 1 THEN 'a_b' ELSE 'a' END AS x, CASE WHEN id = 1 THEN 'c' ELSE 'b_c' END AS y, COUNT(*) FROM sales WHERE id < 3 GROUP BY x, y",
+			[][]string{{"x", "y", "COUNT(*)"}, {"a_b", "c", "1"}, {"a", "b_c", "1"}},
+		},
+		{
+			"SELECT region, score IS NULL AS missing, COUNT(*) FROM sales GROUP BY region, score IS NULL",
+			[][]string{{"region", "missing", "COUNT(*)"}, {"east", "false", "2"}, {"east", "true", "1"}, {"west", "false", "3"}, {"north", "false", "1"}},
+		},
+		{
+			// NULL is one group of its own
+			"SELECT region, score, COUNT(*) FROM sales WHERE region = 'east' GROUP BY region, score",
+			[][]string{{"region", "score", "COUNT(*)"}, {"east", "1.5", "1"}, {"east", "2", "1"}, {"east", "", "1"}},
+		},
+	})
+}
+
+func TestExecuteHaving(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT region, COUNT(*) FROM sales GROUP BY region HAVING COUNT(id) > 2 AND AVG(amount) > 16",
+			[][]string{{"region", "COUNT(*)"}, {"east", "3"}, {"west", "3"}},
+		},
+		{
+			// Aggregates in HAVING need not be selected
+			"SELECT region FROM sales GROUP BY region HAVING MAX(score) > 2 OR MIN(amount) > 30",
+			[][]string{{"region"}, {"west"}, {"north"}},
+		},
+		{
+			// Aliases of output columns can be used
+			"SELECT region, SUM(amount) AS total FROM sales GROUP BY region HAVING total >= 50",
+			[][]string{{"region", "total"}, {"east", "50"}, {"west", "50"}},
+		},
+		{
+			"SELECT region, COUNT(*) AS n FROM sales GROUP BY region HAVING n = 3 AND region <> 'west'",
+			[][]string{{"region", "n"}, {"east", "3"}},
+		},
+		{
+			// NULL condition drops group
+			"SELECT region FROM sales GROUP BY region HAVING AVG(score) IS NULL OR AVG(score) > 3",
+			[][]string{{"region"}, {"north"}},
+		},
+		{
+			// Whole table is one group without GROUP BY
+			"SELECT COUNT(*) FROM sales HAVING COUNT(*) > 10",
+			[][]string{{"COUNT(*)"}},
+		},
+	})
+
+	want := `column "rep" in HAVING must appear in GROUP BY, be an output column or be used in an aggregate function`
+	if err := executeError(t, "SELECT region FROM sales GROUP BY region HAVING rep = 'Ann'"); err.Error() != want {
+		t.Errorf("ungrouped column in HAVING: got %v, want %q", err, want)
+	}
+	if err := syntaxError(t, "SELECT region FROM sales GROUP BY region HAVING ROW_NUMBER() OVER (ORDER BY region) > 1"); err.Message != "window functions are not allowed in HAVING" || err.Pos != 48 {
+		t.Errorf("window function in HAVING: got %v", err)
+	}
+}
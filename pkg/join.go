@@ -0,0 +1,368 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rows of joined tables. Column is found by field name in "global" index, which lists columns of that name
+// of every table, or by table alias (table name when not aliased) then field name
+type JoinTable struct {
+	Header      []string
+	HeaderIndex map[string]JoinHeaderIndex
+	Rows        [][]string
+}
+
+// Scan input of join: table, or result of nested join
+func ScanJoinInput(databasePath string, from interface{}) (JoinTable, error) {
+	if joinExpr, isJoin := from.(JoinExpr); isJoin {
+		return HandleJoinTable(databasePath, joinExpr)
+	}
+	table, _ := from.(TableRef)
+	scanTableInfo, err := ScanTable(databasePath, Stringify(table.Name.Value))
+	if err != nil {
+		return JoinTable{}, err
+	}
+	qualifier := table.Qualifier()
+	headerIndex := map[string]JoinHeaderIndex{
+		"global":  make(JoinHeaderIndex),
+		qualifier: make(JoinHeaderIndex),
+	}
+	for i, field := range scanTableInfo.HeaderRow {
+		headerIndex["global"][field] = append(headerIndex["global"][field], i)
+		headerIndex[qualifier][field] = append(headerIndex[qualifier][field], i)
+	}
+	return JoinTable{
+		Header:      scanTableInfo.HeaderRow,
+		HeaderIndex: headerIndex,
+		Rows:        scanTableInfo.Rows,
+	}, nil
+}
+
+// Join two inputs. Outer joins keep unmatched rows of the outer side padded with NULLs
+func HandleJoinTable(databasePath string, joinExpr JoinExpr) (JoinTable, error) {
+	left, err := ScanJoinInput(databasePath, joinExpr.Left)
+	if err != nil {
+		return JoinTable{}, err
+	}
+	right, err := ScanJoinInput(databasePath, joinExpr.Right)
+	if err != nil {
+		return JoinTable{}, err
+	}
+	// Self-join needs an alias for at least one side, e.g. employees e JOIN employees m
+	for table := range right.HeaderIndex {
+		if _, ok := left.HeaderIndex[table]; ok && table != "global" {
+			return JoinTable{}, fmt.Errorf(`Table name "%v" specified more than once`, table)
+		}
+	}
+	joined := JoinTable{
+		Header:      append(slices.Clone(left.Header), right.Header...),
+		HeaderIndex: MergeJoinHeaderIndex(left.HeaderIndex, right.HeaderIndex, len(left.Header)),
+	}
+	if err := joined.ValidateColumns(joinExpr.Condition); err != nil {
+		return JoinTable{}, err
+	}
+	pairs, err := JoinPairs(joinExpr.Condition, left, right, joined.ExprHeaderIndex())
+	if err != nil {
+		return JoinTable{}, err
+	}
+	joined.Rows = OuterJoinRows(left, right, pairs, joinExpr.Type.Type)
+	return joined, nil
+}
+
+// Header index of joined row: columns of right input are shifted by width of left input
+func MergeJoinHeaderIndex(left, right map[string]JoinHeaderIndex, offset int) map[string]JoinHeaderIndex {
+	merged := map[string]JoinHeaderIndex{}
+	for table, index := range left {
+		merged[table] = make(JoinHeaderIndex)
+		for field, positions := range index {
+			merged[table][field] = slices.Clone(positions)
+		}
+	}
+	for table, index := range right {
+		if _, ok := merged[table]; !ok {
+			merged[table] = make(JoinHeaderIndex)
+		}
+		for field, positions := range index {
+			for _, position := range positions {
+				merged[table][field] = append(merged[table][field], position+offset)
+			}
+		}
+	}
+	return merged
+}
+
+// Flat header index of joined row for expressions. Every column is named "table.field",
+// bare field name only refers to a column whose name is unique among joined tables
+func (table JoinTable) ExprHeaderIndex() map[string]int {
+	index := map[string]int{}
+	for tableName, fields := range table.HeaderIndex {
+		for field, positions := range fields {
+			if len(positions) != 1 {
+				continue
+			}
+			if tableName == "global" {
+				index[field] = positions[0]
+			} else {
+				index[tableName+"."+field] = positions[0]
+			}
+		}
+	}
+	return index
+}
+
+// Position of column in joined row. Name is "table.field" or bare field, which must be unique among joined tables
+func (table JoinTable) ColumnPosition(name string) (int, error) {
+	qualifier, field, isQualified := strings.Cut(name, ".")
+	if !isQualified {
+		qualifier, field = "global", name
+	}
+	fields, ok := table.HeaderIndex[qualifier]
+	if !ok {
+		return -1, fmt.Errorf(`Missing FROM-clause entry for table "%v"`, qualifier)
+	}
+	positions := fields[field]
+	switch len(positions) {
+	case 0:
+		{
+			return -1, fmt.Errorf(`Column "%v" does not exist`, name)
+		}
+	case 1:
+		{
+			return positions[0], nil
+		}
+	default:
+		{
+			return -1, fmt.Errorf(`Column reference "%v" is ambiguous`, name)
+		}
+	}
+}
+
+// Check that every column referenced by expression resolves to exactly one column of joined row
+func (table JoinTable) ValidateColumns(expr interface{}) error {
+	for _, name := range SourceColumns(expr) {
+		if _, err := table.ColumnPosition(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check columns referenced by query over joined rows. HAVING can also refer to output column names
+func (table JoinTable) ValidateQueryColumns(ast AST) error {
+	exprs := []interface{}{ast.Where}
+	for _, col := range ast.Columns {
+		exprs = append(exprs, col.Expr)
+	}
+	for _, expr := range ast.GroupBy {
+		exprs = append(exprs, expr)
+	}
+	for _, condition := range ast.OrderBy {
+		if condition.Column == 0 {
+			exprs = append(exprs, condition.Expr)
+		}
+	}
+	for _, expr := range exprs {
+		if err := table.ValidateColumns(expr); err != nil {
+			return err
+		}
+	}
+	for _, name := range SourceColumns(ast.Having) {
+		if OutputColumnPosition(Token{Type: TokenIdent, Value: name}, ast.Columns) >= 0 {
+			continue
+		}
+		if _, err := table.ColumnPosition(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matching pairs of left and right row positions, in order of left rows then of right rows.
+// Equalities between columns of both inputs in ON condition are hash join keys and the rest of condition
+// filters pairs with equal keys. Without such equality every pair is tested, CROSS JOIN keeps every pair
+func JoinPairs(condition interface{}, left, right JoinTable, headerIndex map[string]int) ([][2]int, error) {
+	leftKeys, rightKeys, residual := JoinKeyColumns(condition, left, right)
+	pairs := [][2]int{}
+	if len(leftKeys) > 0 {
+		pairs = HashJoinPairs(left.Rows, leftKeys, right.Rows, rightKeys)
+	} else {
+		for i := range left.Rows {
+			for j := range right.Rows {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	if residual == nil {
+		return pairs, nil
+	}
+
+	matched := [][2]int{}
+	for _, pair := range pairs {
+		isValid, err := Eval(residual, JoinRow(left.Rows[pair[0]], right.Rows[pair[1]]), headerIndex)
+		if err != nil {
+			return nil, err
+		}
+		if isValid.IsTrue() {
+			matched = append(matched, pair)
+		}
+	}
+	return matched, nil
+}
+
+// Split ON condition into conjuncts. Equality of a column only in left input and a column only in right input
+// gives key positions in each input, other conjuncts are combined back into residual condition
+func JoinKeyColumns(condition interface{}, left, right JoinTable) ([]int, []int, interface{}) {
+	leftIndex, rightIndex := left.ExprHeaderIndex(), right.ExprHeaderIndex()
+	leftKeys, rightKeys := []int{}, []int{}
+	var residual interface{}
+	for _, conjunct := range Conjuncts(condition) {
+		if leftName, rightName, isColumns := ColumnEquality(conjunct); isColumns {
+			if _, isRight := rightIndex[leftName]; isRight {
+				leftName, rightName = rightName, leftName
+			}
+			leftKey, isLeftKey := leftIndex[leftName]
+			rightKey, isRightKey := rightIndex[rightName]
+			_, isLeftInRight := rightIndex[leftName]
+			_, isRightInLeft := leftIndex[rightName]
+			if isLeftKey && isRightKey && !isLeftInRight && !isRightInLeft {
+				leftKeys = append(leftKeys, leftKey)
+				rightKeys = append(rightKeys, rightKey)
+				continue
+			}
+		}
+		if residual == nil {
+			residual = conjunct
+		} else {
+			residual = BinaryExpr{Left: residual, Op: Token{Type: TokenAnd, Value: "AND"}, Right: conjunct}
+		}
+	}
+	return leftKeys, rightKeys, residual
+}
+
+// Names of both columns of equality between two columns, e.g. a.id = b.a_id
+func ColumnEquality(expr interface{}) (string, string, bool) {
+	binary, isBinary := expr.(BinaryExpr)
+	if !isBinary || binary.Op.Type != TokenEqual {
+		return "", "", false
+	}
+	leftColumn, isLeftToken := binary.Left.(Token)
+	rightColumn, isRightToken := binary.Right.(Token)
+	if !isLeftToken || !isRightToken || leftColumn.Type != TokenIdent || rightColumn.Type != TokenIdent {
+		return "", "", false
+	}
+	return Stringify(leftColumn.Value), Stringify(rightColumn.Value), true
+}
+
+// Operands of top-level AND chain
+func Conjuncts(condition interface{}) []interface{} {
+	if condition == nil {
+		return nil
+	}
+	if binary, isBinary := condition.(BinaryExpr); isBinary && binary.Op.Type == TokenAnd {
+		return append(Conjuncts(binary.Left), Conjuncts(binary.Right)...)
+	}
+	return []interface{}{condition}
+}
+
+// Hash join on key columns. Hash table is built on the smaller input and probed with the larger one.
+// Pairs are returned in order of left rows, then of right rows, whichever side is built.
+// Keys are compared as typed values, so 1 matches 1.0, and NULL matches nothing
+func HashJoinPairs(left [][]string, leftKeys []int, right [][]string, rightKeys []int) [][2]int {
+	isBuildLeft := len(left) < len(right)
+	build, buildKeys, probe, probeKeys := right, rightKeys, left, leftKeys
+	if isBuildLeft {
+		build, buildKeys, probe, probeKeys = left, leftKeys, right, rightKeys
+	}
+
+	buckets := map[string][]int{}
+	for i, row := range build {
+		if key, ok := JoinKey(row, buildKeys); ok {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	pairs := [][2]int{}
+	for i, row := range probe {
+		key, ok := JoinKey(row, probeKeys)
+		if !ok {
+			continue
+		}
+		for _, j := range buckets[key] {
+			if isBuildLeft {
+				pairs = append(pairs, [2]int{j, i})
+			} else {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	if isBuildLeft {
+		slices.SortFunc(pairs, func(pair1, pair2 [2]int) int {
+			return cmp.Or(cmp.Compare(pair1[0], pair2[0]), cmp.Compare(pair1[1], pair2[1]))
+		})
+	}
+	return pairs
+}
+
+// Joined rows of matching pairs. Unmatched left row of LEFT and FULL JOIN follows its position,
+// unmatched right rows of RIGHT and FULL JOIN come last
+func OuterJoinRows(left, right JoinTable, pairs [][2]int, joinType TokenType) [][]string {
+	isLeftOuter := joinType == TokenLeftJoin || joinType == TokenFullJoin
+	isRightOuter := joinType == TokenRightJoin || joinType == TokenFullJoin
+	leftNulls := NullRow(len(left.Header))
+	rightNulls := NullRow(len(right.Header))
+	rightMatched := make([]bool, len(right.Rows))
+
+	rows := make([][]string, 0, len(pairs))
+	pairIdx := 0
+	for i, leftRow := range left.Rows {
+		isMatched := false
+		for ; pairIdx < len(pairs) && pairs[pairIdx][0] == i; pairIdx++ {
+			j := pairs[pairIdx][1]
+			rows = append(rows, JoinRow(leftRow, right.Rows[j]))
+			rightMatched[j] = true
+			isMatched = true
+		}
+		if !isMatched && isLeftOuter {
+			rows = append(rows, JoinRow(leftRow, rightNulls))
+		}
+	}
+	if isRightOuter {
+		for j, rightRow := range right.Rows {
+			if !rightMatched[j] {
+				rows = append(rows, JoinRow(leftNulls, rightRow))
+			}
+		}
+	}
+	return rows
+}
+
+func JoinRow(left, right []string) []string {
+	row := make([]string, 0, len(left)+len(right))
+	row = append(row, left...)
+	return append(row, right...)
+}
+
+// Row of NULL cells padding unmatched side of outer join
+func NullRow(width int) []string {
+	row := make([]string, width)
+	for i := range row {
+		row[i] = NullCell
+	}
+	return row
+}
+
+// Hash key of key cells of row, false when any of them is NULL
+func JoinKey(row []string, keys []int) (string, bool) {
+	values := []Value{}
+	for _, key := range keys {
+		value := ParseValue(row[key])
+		if value.IsNull() {
+			return "", false
+		}
+		values = append(values, value)
+	}
+	return TypedKey(values), true
+}
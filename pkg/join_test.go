@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashJoinPairs(t *testing.T) {
+	small := [][]string{{"1"}, {"2"}, {""}}
+	large := [][]string{{"2"}, {"1.0"}, {"3"}, {"1"}, {""}}
+	tests := []struct {
+		name      string
+		left      [][]string
+		leftKeys  []int
+		right     [][]string
+		rightKeys []int
+		want      [][2]int
+	}{
+		{
+			// Right input is built, pairs come in order of probed left rows
+			"build right", large, []int{0}, small, []int{0},
+			[][2]int{{0, 1}, {1, 0}, {3, 0}},
+		},
+		{
+			// Left input is built, pairs are still in order of left rows then right rows
+			"build left", small, []int{0}, large, []int{0},
+			[][2]int{{0, 1}, {0, 3}, {1, 0}},
+		},
+		{
+			// Every left row with key pairs with every right row with that key
+			"duplicate keys",
+			[][]string{{"a"}, {"b"}, {"a"}}, []int{0},
+			[][]string{{"a"}, {"c"}, {"a"}, {"b"}}, []int{0},
+			[][2]int{{0, 0}, {0, 2}, {1, 3}, {2, 0}, {2, 2}},
+		},
+		{
+			"NULL keys never match",
+			[][]string{{""}, {"NULL"}, {"1"}}, []int{0},
+			[][]string{{"NULL"}, {""}, {"null"}, {"1"}}, []int{0},
+			[][2]int{{2, 3}},
+		},
+		{
+			// Composite key matches only when every key column matches, NULL in any column never matches
+			"composite key",
+			[][]string{{"1", "a"}, {"1", "b"}, {"1", ""}}, []int{0, 1},
+			[][]string{{"b", "1"}, {"a", "2"}, {"", "1"}}, []int{1, 0},
+			[][2]int{{1, 0}},
+		},
+		{
+			"no rows", nil, []int{0}, small, []int{0},
+			[][2]int{},
+		},
+	}
+	for _, test := range tests {
+		if got := HashJoinPairs(test.left, test.leftKeys, test.right, test.rightKeys); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: HashJoinPairs = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOuterJoinRows(t *testing.T) {
+	left := JoinTable{Header: []string{"id"}, Rows: [][]string{{"1"}, {"2"}, {"3"}, {"4"}}}
+	right := JoinTable{Header: []string{"ref", "note"}, Rows: [][]string{{"5", "e"}, {"3", "c"}, {"6", "f"}, {"1", "a"}, {"1", "b"}}}
+	pairs := [][2]int{{0, 3}, {0, 4}, {2, 1}}
+	tests := []struct {
+		joinType TokenType
+		want     [][]string
+	}{
+		{TokenJoin, [][]string{{"1", "1", "a"}, {"1", "1", "b"}, {"3", "3", "c"}}},
+		{
+			// Unmatched left rows keep their position among matched ones
+			TokenLeftJoin,
+			[][]string{{"1", "1", "a"}, {"1", "1", "b"}, {"2", NullCell, NullCell}, {"3", "3", "c"}, {"4", NullCell, NullCell}},
+		},
+		{
+			// Unmatched right rows come last, in order of right rows
+			TokenRightJoin,
+			[][]string{{"1", "1", "a"}, {"1", "1", "b"}, {"3", "3", "c"}, {NullCell, "5", "e"}, {NullCell, "6", "f"}},
+		},
+		{
+			TokenFullJoin,
+			[][]string{
+				{"1", "1", "a"}, {"1", "1", "b"}, {"2", NullCell, NullCell}, {"3", "3", "c"}, {"4", NullCell, NullCell},
+				{NullCell, "5", "e"}, {NullCell, "6", "f"},
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := OuterJoinRows(left, right, pairs, test.joinType); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("OuterJoinRows(%v) = %q, want %q", test.joinType, got, test.want)
+		}
+	}
+}
+
+func TestExecuteJoin(t *testing.T) {
+	tests := []queryTest{
+		{
+			"SELECT c.name, o.oid FROM customers c JOIN orders o ON c.id = o.cust_id",
+			[][]string{{"name", "oid"}, {"Ann", "10"}, {"Ann", "11"}, {"Ann", "14"}, {"Ben", "12"}},
+		},
+		{
+			"SELECT c.name FROM customers c LEFT JOIN orders o ON c.id = o.cust_id WHERE o.oid IS NULL",
+			[][]string{{"name"}, {"Cid"}},
+		},
+		{
+			"SELECT c.name, COALESCE(o.oid, 'none') FROM customers c LEFT JOIN orders o ON c.id = o.cust_id AND o.amount > 4",
+			[][]string{{"name", "COALESCE(o.oid, 'none')"}, {"Ann", "10"}, {"Ann", "11"}, {"Ben", "none"}, {"Cid", "none"}},
+		},
+		{
+			"SELECT COUNT(o.oid), COUNT(*) FROM customers c LEFT JOIN orders o ON c.id = o.cust_id",
+			[][]string{{"COUNT(o.oid)", "COUNT(*)"}, {"4", "5"}},
+		},
+		{
+			"SELECT c.name, o.oid FROM customers c RIGHT JOIN orders o ON c.id = o.cust_id WHERE c.id IS NULL",
+			[][]string{{"name", "oid"}, {"", "13"}},
+		},
+		{
+			"SELECT c.name, o.oid FROM customers c FULL JOIN orders o ON c.id = o.cust_id WHERE c.id IS NULL OR o.oid IS NULL",
+			[][]string{{"name", "oid"}, {"Cid", ""}, {"", "13"}},
+		},
+	}
+	runQueryTests(t, tests)
+
+	// Padding of outer join is NULL whatever NULL markers are set
+	setNullMarkers(t, "NULL")
+	runQueryTests(t, tests)
+}
+
+func TestExecuteCrossAndConditionJoins(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT c.name, o.oid FROM customers c CROSS JOIN orders o WHERE o.oid < 12",
+			[][]string{{"name", "oid"}, {"Ann", "10"}, {"Ann", "11"}, {"Ben", "10"}, {"Ben", "11"}, {"Cid", "10"}, {"Cid", "11"}},
+		},
+		{
+			"SELECT c.name, o.oid FROM customers c, orders o WHERE c.id = o.cust_id AND o.amount > 4",
+			[][]string{{"name", "oid"}, {"Ann", "10"}, {"Ann", "11"}},
+		},
+		{
+			// Composite key, both equalities must hold
+			"SELECT c.name, o.oid FROM customers c JOIN orders o ON c.id = o.cust_id AND o.oid = c.id + 9",
+			[][]string{{"name", "oid"}, {"Ann", "10"}},
+		},
+		{
+			// Non-equi conditions fall back to nested loop
+			"SELECT c.name, o.oid FROM customers c JOIN orders o ON o.amount > c.id * 3",
+			[][]string{{"name", "oid"}, {"Ann", "10"}, {"Ann", "11"}, {"Ann", "13"}, {"Ben", "11"}, {"Ben", "13"}},
+		},
+		{
+			"SELECT c.name, o.oid FROM customers c LEFT JOIN orders o ON o.amount BETWEEN c.id * 2 AND c.id * 2 + 1",
+			[][]string{{"name", "oid"}, {"Ann", "12"}, {"Ann", "14"}, {"Ben", "10"}, {"Cid", "11"}},
+		},
+		{
+			"SELECT c.name, o.oid FROM customers c LEFT JOIN orders o ON o.amount < c.id * 2",
+			[][]string{{"name", "oid"}, {"Ann", ""}, {"Ben", "12"}, {"Ben", "14"}, {"Cid", "10"}, {"Cid", "12"}, {"Cid", "14"}},
+		},
+	})
+}
+
+func TestExecuteTableAliases(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT e.name, m.name AS manager FROM staff e LEFT JOIN staff m ON e.manager_id = m.id",
+			[][]string{{"name", "manager"}, {"Ann", ""}, {"Bob", "Ann"}, {"Cat", "Ann"}, {"Dan", "Bob"}},
+		},
+		{
+			"SELECT m.name, COUNT(*) FROM staff AS e JOIN staff AS m ON e.manager_id = m.id GROUP BY m.name ORDER BY m.name",
+			[][]string{{"name", "COUNT(*)"}, {"Ann", "2"}, {"Bob", "1"}},
+		},
+		{
+			"SELECT s.name FROM staff AS s WHERE s.id > 2 ORDER BY s.name DESC",
+			[][]string{{"name"}, {"Dan"}, {"Cat"}},
+		},
+		{
+			"SELECT staff.name FROM staff WHERE staff.id > 2",
+			[][]string{{"name"}, {"Cat"}, {"Dan"}},
+		},
+	})
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT name FROM staff e JOIN staff m ON e.manager_id = m.id", `Column reference "name" is ambiguous`},
+		{"SELECT c.missing FROM customers c JOIN orders o ON c.id = o.cust_id", `Column "c.missing" does not exist`},
+		{"SELECT x.name FROM customers c JOIN orders o ON c.id = o.cust_id", `Missing FROM-clause entry for table "x"`},
+	}
+	for _, test := range tests {
+		if err := executeError(t, test.sql); err.Error() != test.want {
+			t.Errorf("%v: got error %q, want %q", test.sql, err, test.want)
+		}
+	}
+}
+
+func TestExecuteQualifiedColumns(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT customers.name, SUM(orders.amount) FROM customers JOIN orders ON customers.id = orders.cust_id " +
+				"WHERE orders.amount > 2 GROUP BY customers.name HAVING SUM(orders.amount) > 5 ORDER BY SUM(orders.amount) DESC",
+			[][]string{{"name", "SUM(orders.amount)"}, {"Ann", "12"}},
+		},
+		{
+			"SELECT c.name, o.amount FROM customers c JOIN orders o ON c.id = o.cust_id ORDER BY o.amount DESC, c.name LIMIT 2",
+			[][]string{{"name", "amount"}, {"Ann", "7"}, {"Ann", "5"}},
+		},
+		{
+			"SELECT c.id, COUNT(o.oid), MAX(o.amount) FROM customers c LEFT JOIN orders o ON c.id = o.cust_id GROUP BY c.id ORDER BY c.id",
+			[][]string{{"id", "COUNT(o.oid)", "MAX(o.amount)"}, {"1", "3", "7"}, {"2", "1", "3"}, {"3", "0", ""}},
+		},
+		{
+			// Unambiguous column can be used without table name
+			"SELECT name, oid FROM customers c JOIN orders o ON c.id = o.cust_id WHERE amount < 4 ORDER BY oid",
+			[][]string{{"name", "oid"}, {"Ben", "12"}, {"Ann", "14"}},
+		},
+	})
+}
@@ -8,28 +8,27 @@ type ParserFrom struct {
 	tokens  []Token
 	pointer int
 	current Token
-	opTable map[TokenType]OpInfoFrom
-}
-
-type NudFrom func() Token
-type LedFrom func(left interface{}) any
-
-type OpInfoFrom struct {
-	lbp int
-	nud NudFrom
-	led LedFrom
 }
 
 type JoinExpr struct {
 	Type      Token
 	Left      interface{}
 	Right     interface{}
-	Condition interface{}
+	Condition interface{} // nil for CROSS JOIN
+}
+
+// Table of FROM with optional alias, e.g. employees AS e or employees e
+type TableRef struct {
+	Name  Token
+	Alias string
 }
 
-type TableIdentifier struct {
-	Table Token
-	Field Token
+// Name qualifying columns of table: alias if given, table name otherwise
+func (table TableRef) Qualifier() string {
+	if len(table.Alias) > 0 {
+		return table.Alias
+	}
+	return Stringify(table.Name.Value)
 }
 
 func NewParserFrom(tokens []Token) *ParserFrom {
@@ -37,42 +36,10 @@ func NewParserFrom(tokens []Token) *ParserFrom {
 		tokens:  tokens,
 		current: tokens[0],
 		pointer: 0,
-		opTable: map[TokenType]OpInfoFrom{},
 	}
 	return p
 }
 
-// Register binding power for common operators
-func (p *ParserFrom) RegisterInfix(tokenType TokenType, lbp int) {
-	(*p).opTable[tokenType] = OpInfoFrom{
-		lbp: lbp,
-		led: func(left interface{}) interface{} {
-			op := p.tokens[p.pointer-1]
-			right := p.ParseOnCondition(lbp)
-			return BinaryExpr{
-				Left:  left,
-				Op:    op,
-				Right: right,
-			}
-		},
-	}
-}
-
-// Register precedence for prefix token
-func (p *ParserFrom) RegisterPrefix(tokenType TokenType, lbp int) {
-	(*p).opTable[tokenType] = OpInfoFrom{
-		lbp: lbp,
-		nud: func() Token {
-			return p.tokens[p.pointer-1]
-		},
-	}
-}
-
-// Get binding power of token
-func (p *ParserFrom) GetLBP(tokenType TokenType) int {
-	return p.opTable[tokenType].lbp
-}
-
 func (p *ParserFrom) Advance() {
 	if p.pointer < len(p.tokens)-1 {
 		p.pointer++
@@ -80,81 +47,98 @@ func (p *ParserFrom) Advance() {
 	}
 }
 
-func (p *ParserFrom) ParseJoin(joinToken Token, left interface{}) JoinExpr {
-	right, condition := p.ParserFromExpression(0)
-	return JoinExpr{
-		Type:      joinToken,
-		Left:      left,
-		Right:     right,
-		Condition: condition,
+// Handle parse right table of join and its ON condition. CROSS JOIN and comma join have no condition
+func (p *ParserFrom) ParseJoin(joinToken Token, left interface{}) (JoinExpr, error) {
+	right, err := p.ParseTable()
+	if err != nil {
+		return JoinExpr{}, err
 	}
-}
-
-func (p *ParserFrom) ParseTableIdentifier() TableIdentifier {
-	table := p.current
-	p.Advance()
-
-	if p.current.Type != TokenDot {
-		panic("Syntax error")
+	if joinToken.Type == TokenComma {
+		joinToken.Type = TokenCrossJoin
+	}
+	join := JoinExpr{
+		Type:  joinToken,
+		Left:  left,
+		Right: right,
 	}
 
+	isCross := joinToken.Type == TokenCrossJoin
+	if p.current.Type != TokenOn {
+		if !isCross {
+			return JoinExpr{}, UnexpectedTokenError(p.current, TokenOn)
+		}
+		return join, nil
+	}
+	if isCross {
+		return JoinExpr{}, NewSyntaxError(p.current, "CROSS JOIN can not have ON condition")
+	}
 	p.Advance()
-
-	field := p.current
-	return TableIdentifier{
-		Table: table,
-		Field: field,
+	join.Condition, err = p.ParseOnCondition()
+	if err != nil {
+		return JoinExpr{}, err
 	}
+	return join, nil
 }
 
-func (p *ParserFrom) ParseOnCondition(minBP int) interface{} {
-
-	var left interface{}
-	t := p.current
-	if t.Type == TokenIdent {
-		left = p.ParseTableIdentifier()
+// Handle parse table name and its alias, with or without AS
+func (p *ParserFrom) ParseTable() (interface{}, error) {
+	name := p.current
+	if _, err := Expect(name, TokenIdent); err != nil {
+		return nil, err
+	}
+	p.Advance()
+	table := TableRef{Name: name}
+	if p.current.Type == TokenAs {
 		p.Advance()
+		if _, err := Expect(p.current, TokenIdent); err != nil {
+			return nil, err
+		}
 	}
-
-	if t.Type == TokenNumber || t.Type == TokenString {
+	if p.current.Type == TokenIdent {
+		table.Alias = Stringify(p.current.Value)
 		p.Advance()
-		left = p.opTable[t.Type].nud()
 	}
+	return table, nil
+}
 
-	for p.current.Type != TokenEOF && p.GetLBP(p.current.Type) > minBP {
-		t = p.current
+// Handle parse ON condition as scalar expression, columns are qualified by table, e.g. a.id = b.a_id
+func (p *ParserFrom) ParseOnCondition() (interface{}, error) {
+	exprParser := NewParser(p.tokens[p.pointer:])
+	RegisterExpressionOperators(exprParser)
+	condition, err := exprParser.ParseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if aggregates := CollectAggregates(condition); len(aggregates) > 0 {
+		return nil, NewSyntaxError(aggregates[0].Name, "aggregate functions are not allowed in JOIN condition")
+	}
+	if windows := CollectWindows(condition); len(windows) > 0 {
+		return nil, NewSyntaxError(windows[0].Name, "window functions are not allowed in JOIN condition")
+	}
+	for i := 0; i < exprParser.pointer; i++ {
 		p.Advance()
-		left = p.opTable[t.Type].led(left)
 	}
-
-	return left
+	return condition, nil
 }
 
 func CheckJoinToken(token Token) bool {
-	joinTokenType := []TokenType{TokenJoin, TokenLeftJoin, TokenRightJoin}
+	joinTokenType := []TokenType{TokenJoin, TokenLeftJoin, TokenRightJoin, TokenFullJoin, TokenCrossJoin, TokenComma}
 	return slices.Contains(joinTokenType, token.Type)
 }
 
-func (p *ParserFrom) ParserFromExpression(minBP int) (interface{}, interface{}) {
-	t := p.current
-	p.Advance()
-	var left interface{}
-	var condition interface{}
-
-	if t.Type == TokenIdent {
-		left = t
+// Handle parse FROM: table followed by joins, which associate to the left
+func (p *ParserFrom) ParserFromExpression() (interface{}, error) {
+	left, err := p.ParseTable()
+	if err != nil {
+		return nil, err
 	}
-
-	if p.current.Type == TokenOn {
-		p.Advance()
-		condition = p.ParseOnCondition(0)
-		// condition = nil
-	}
-
-	for CheckJoinToken(p.current) && condition == nil {
+	for CheckJoinToken(p.current) {
 		joinToken := p.current
 		p.Advance()
-		left = p.ParseJoin(joinToken, left)
+		left, err = p.ParseJoin(joinToken, left)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return left, condition
+	return left, nil
 }
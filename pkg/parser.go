@@ -2,6 +2,8 @@ package pkg
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 )
 
 type Parser struct {
@@ -11,8 +13,8 @@ type Parser struct {
 	opTable map[TokenType]OpInfo
 }
 
-type Nud func(p *Parser) Token
-type Led func(left interface{}) any
+type Nud func(p *Parser) (Expr, error)
+type Led func(left interface{}) (any, error)
 
 type OpInfo struct {
 	lbp int
@@ -37,13 +39,16 @@ func NewParser(tokens []Token) *Parser {
 func (p *Parser) RegisterBetweenInfix(tokenType TokenType, lbp int) {
 	(*p).opTable[tokenType] = OpInfo{
 		lbp: lbp,
-		led: func(left interface{}) interface{} {
-			ranges := p.ParseBetweenExpression()
+		led: func(left interface{}) (interface{}, error) {
+			ranges, err := p.ParseBetweenExpression()
+			if err != nil {
+				return nil, err
+			}
 			return BetweenExpr{
 				Expr:  left,
 				Lower: ranges[0],
 				Upper: ranges[1],
-			}
+			}, nil
 		},
 	}
 }
@@ -52,12 +57,15 @@ func (p *Parser) RegisterBetweenInfix(tokenType TokenType, lbp int) {
 func (p *Parser) RegisterInInfix(tokenType TokenType, lbp int) {
 	(*p).opTable[tokenType] = OpInfo{
 		lbp: lbp,
-		led: func(left interface{}) interface{} {
-			collector := p.ParseInExpression()
+		led: func(left interface{}) (interface{}, error) {
+			collector, err := p.ParseInExpression()
+			if err != nil {
+				return nil, err
+			}
 			return InExpr{
 				Expr:      left,
 				Collector: collector,
-			}
+			}, nil
 		},
 	}
 }
@@ -66,14 +74,17 @@ func (p *Parser) RegisterInInfix(tokenType TokenType, lbp int) {
 func (p *Parser) RegisterInfix(tokenType TokenType, lbp int) {
 	(*p).opTable[tokenType] = OpInfo{
 		lbp: lbp,
-		led: func(left interface{}) interface{} {
+		led: func(left interface{}) (interface{}, error) {
 			op := p.tokens[p.pointer-1]
-			right := p.ParseExpression(lbp)
+			right, err := p.ParseExpression(lbp)
+			if err != nil {
+				return nil, err
+			}
 			return BinaryExpr{
 				Left:  left,
 				Op:    op,
 				Right: right,
-			}
+			}, nil
 		},
 	}
 }
@@ -82,8 +93,201 @@ func (p *Parser) RegisterInfix(tokenType TokenType, lbp int) {
 func (p *Parser) RegisterPrefix(tokenType TokenType, lbp int) {
 	(*p).opTable[tokenType] = OpInfo{
 		lbp: lbp,
-		nud: func(p *Parser) Token {
-			return p.tokens[p.pointer-1]
+		nud: func(p *Parser) (Expr, error) {
+			return p.tokens[p.pointer-1], nil
+		},
+	}
+}
+
+// Register prefix for identifier, which becomes function call when followed by "("
+func (p *Parser) RegisterIdentifierPrefix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		nud: func(p *Parser) (Expr, error) {
+			name := p.tokens[p.pointer-1]
+			if p.current.Type == TokenDot {
+				return p.ParseQualifiedIdentifier(name)
+			}
+			if p.current.Type != TokenLParen {
+				return name, nil
+			}
+			if _, isAggregate := GetAggregateFunction(Stringify(name.Value)); isAggregate {
+				return p.ParseAggregateCall(name)
+			}
+			if _, isWindow := GetWindowFunction(Stringify(name.Value)); isWindow {
+				return p.ParseWindowFunctionCall(name)
+			}
+			if strings.ToUpper(Stringify(name.Value)) == "GROUPING" {
+				return p.ParseGroupingCall(name)
+			}
+			// CAST and EXTRACT use keywords between arguments instead of comma
+			var args []Expr
+			var err error
+			switch strings.ToUpper(Stringify(name.Value)) {
+			case "CAST":
+				args, err = p.ParseCastArguments()
+			case "EXTRACT":
+				args, err = p.ParseExtractArguments()
+			default:
+				args, err = p.ParseArguments()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return NewFunctionExpr(name, args)
+		},
+	}
+}
+
+// Handle parse column qualified by table, e.g. orders.id, into identifier named "orders.id"
+func (p *Parser) ParseQualifiedIdentifier(table Token) (Expr, error) {
+	p.Advance()
+	field, err := p.Expect(TokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	return Token{
+		Type:   TokenIdent,
+		Value:  fmt.Sprintf("%v.%v", table.Value, field.Value),
+		Pos:    table.Pos,
+		EndPos: field.EndPos,
+	}, nil
+}
+
+// Infix operators that can be negated with NOT, e.g. NOT IN, NOT BETWEEN
+var negatableInfix = []TokenType{TokenIn, TokenBetween, TokenLike, TokenILike, TokenRegexp}
+
+// Register NOT as prefix operator (NOT expr) and as infix negation (a NOT IN ...)
+func (p *Parser) RegisterNotOperator(tokenType TokenType, prefixBp int, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		nud: func(p *Parser) (Expr, error) {
+			op := p.tokens[p.pointer-1]
+			expr, err := p.ParseExpression(prefixBp)
+			if err != nil {
+				return nil, err
+			}
+			return UnaryExpr{
+				Op:   op,
+				Expr: expr,
+			}, nil
+		},
+		led: func(left interface{}) (interface{}, error) {
+			t := p.current
+			if !slices.Contains(negatableInfix, t.Type) {
+				return nil, UnexpectedTokenError(t, negatableInfix...)
+			}
+			p.Advance()
+			expr, err := p.opTable[t.Type].led(left)
+			if err != nil {
+				return nil, err
+			}
+			return Negate(expr), nil
+		},
+	}
+}
+
+// Register prefix operator, e.g. unary minus
+func (p *Parser) RegisterUnaryPrefix(tokenType TokenType, prefixBp int) {
+	info := (*p).opTable[tokenType]
+	info.nud = func(p *Parser) (Expr, error) {
+		op := p.tokens[p.pointer-1]
+		expr, err := p.ParseExpression(prefixBp)
+		if err != nil {
+			return nil, err
+		}
+		return UnaryExpr{
+			Op:   op,
+			Expr: expr,
+		}, nil
+	}
+	(*p).opTable[tokenType] = info
+}
+
+// Register "(" prefix for parenthesized sub-expression
+func (p *Parser) RegisterGroupPrefix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		nud: func(p *Parser) (Expr, error) {
+			expr, err := p.ParseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.Expect(TokenRParen); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		},
+	}
+}
+
+// Register CASE prefix: CASE [operand] WHEN ... THEN ... [ELSE ...] END
+func (p *Parser) RegisterCasePrefix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		nud: func(p *Parser) (Expr, error) {
+			caseExpr := CaseExpr{}
+			if p.current.Type != TokenWhen {
+				operand, err := p.ParseExpression(0)
+				if err != nil {
+					return nil, err
+				}
+				caseExpr.Operand = operand
+			}
+			if p.current.Type != TokenWhen {
+				return nil, UnexpectedTokenError(p.current, TokenWhen)
+			}
+			for p.current.Type == TokenWhen {
+				p.Advance()
+				condition, err := p.ParseExpression(0)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := p.Expect(TokenThen); err != nil {
+					return nil, err
+				}
+				result, err := p.ParseExpression(0)
+				if err != nil {
+					return nil, err
+				}
+				caseExpr.Whens = append(caseExpr.Whens, WhenClause{
+					Condition: condition,
+					Result:    result,
+				})
+			}
+			if p.current.Type == TokenElse {
+				p.Advance()
+				elseExpr, err := p.ParseExpression(0)
+				if err != nil {
+					return nil, err
+				}
+				caseExpr.Else = elseExpr
+			}
+			if p.current.Type != TokenEnd {
+				return nil, UnexpectedTokenError(p.current, TokenWhen, TokenElse, TokenEnd)
+			}
+			p.Advance()
+			return caseExpr, nil
+		},
+	}
+}
+
+// Register binding power for IS [NOT] NULL operator
+func (p *Parser) RegisterIsNullInfix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		led: func(left interface{}) (interface{}, error) {
+			isNot := p.current.Type == TokenNot
+			if isNot {
+				p.Advance()
+			}
+			if _, err := p.Expect(TokenNull); err != nil {
+				return nil, err
+			}
+			return IsNullExpr{
+				Expr: left,
+				Not:  isNot,
+			}, nil
 		},
 	}
 }
@@ -101,56 +305,158 @@ func (p *Parser) GetLBP(tokenType TokenType) int {
 	return p.opTable[tokenType].lbp
 }
 
+// Check current token type then move to next token
+func (p *Parser) Expect(expectedType TokenType) (Token, error) {
+	t := p.current
+	if t.Type != expectedType {
+		return t, UnexpectedTokenError(t, expectedType)
+	}
+	p.Advance()
+	return t, nil
+}
+
 // Pratt parser function
-func (p *Parser) ParseExpression(minBp int) Expr {
+func (p *Parser) ParseExpression(minBp int) (Expr, error) {
 	t := p.current
+	opInfo, ok := p.opTable[t.Type]
+	if !ok || opInfo.nud == nil {
+		return nil, NewSyntaxError(t, fmt.Sprintf("expected expression, found %v", t.Type))
+	}
 	p.Advance()
-	var left Expr
-	if t.Type == TokenNumber || t.Type == TokenIdent || t.Type == TokenString {
-		left = p.opTable[t.Type].nud(p)
+	left, err := opInfo.nud(p)
+	if err != nil {
+		return nil, err
 	}
 
 	for p.current.Type != TokenEOF && p.GetLBP(p.current.Type) > minBp {
 		t = p.current
 		p.Advance()
-		left = p.opTable[t.Type].led(left)
+		left, err = p.opTable[t.Type].led(left)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return left
+	return left, nil
 }
 
-// Handle parse tokens into BETWEEN expression format
-func (p *Parser) ParseBetweenExpression() []interface{} {
-	ranges := []interface{}{} // Store lower and upper tokens
-	for len(ranges) < 2 {
-		t := p.current
-		if t.Type != TokenAnd {
-			ranges = append(ranges, t)
-		}
+// Handle parse comma separated expressions within "(" and ")"
+func (p *Parser) ParseArguments() ([]Expr, error) {
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	args := []Expr{}
+	if p.current.Type == TokenRParen {
 		p.Advance()
+		return args, nil
 	}
-	return ranges
-}
+	for {
+		arg, err := p.ParseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
 
-// Handle parse tokens into IN expression format
-func (p *Parser) ParseInExpression() []interface{} {
-	if p.current.Type != TokenLParen {
-		panic("Missing '(' symbol")
+		if p.current.Type == TokenRParen {
+			break
+		}
+		if p.current.Type != TokenComma {
+			return nil, UnexpectedTokenError(p.current, TokenComma, TokenRParen)
+		}
+		p.Advance()
 	}
 	p.Advance()
-	collector := []interface{}{} // Store tokens within "(" and ")"
-	for p.current.Type != TokenRParen {
+	return args, nil
+}
+
+// Handle parse ORDER BY list within aggregate function call or OVER clause
+func (p *Parser) ParseOrderByList() ([]OrderBySingle, error) {
+	orderBy := []OrderBySingle{}
+	for {
+		expr, err := p.ParseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		condition := OrderBySingle{
+			Field:     ExprString(expr),
+			Expr:      expr,
+			Direction: TokenAsc,
+		}
+		if p.current.Type == TokenAsc || p.current.Type == TokenDesc {
+			condition.Direction = p.current.Type
+			p.Advance()
+		}
+		if p.current.Type == TokenNullsFirst || p.current.Type == TokenNullsLast {
+			condition.Nulls = p.current.Type
+			p.Advance()
+		}
+		orderBy = append(orderBy, condition)
 		if p.current.Type != TokenComma {
-			collector = append(collector, p.current)
+			return orderBy, nil
 		}
 		p.Advance()
 	}
-	p.Advance()
-	return collector
+}
+
+// Handle parse tokens into BETWEEN expression format
+func (p *Parser) ParseBetweenExpression() ([]interface{}, error) {
+	bp := p.GetLBP(TokenBetween)
+	lower, err := p.ParseExpression(bp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(TokenAnd); err != nil {
+		return nil, err
+	}
+	upper, err := p.ParseExpression(bp)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{lower, upper}, nil
+}
+
+// Handle parse tokens into IN expression format
+func (p *Parser) ParseInExpression() ([]interface{}, error) {
+	lParen := p.current
+	args, err := p.ParseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, NewSyntaxError(lParen, "IN list can not be empty")
+	}
+	collector := []interface{}{} // Store expressions within "(" and ")"
+	for _, arg := range args {
+		collector = append(collector, arg)
+	}
+	return collector, nil
+}
+
+// Convert literal token into typed value
+func TokenToValue(token Token) Value {
+	switch token.Type {
+	case TokenString:
+		return StringValue(Stringify(token.Value))
+	case TokenNumber, TokenBoolean:
+		return ToValue(token.Value)
+	case TokenNull:
+		return NullValue()
+	default:
+		return StringValue(Stringify(token.Value))
+	}
+}
+
+// Get typed value of column in row
+func ColumnValue(name string, row []string, headerIndex map[string]int) (Value, error) {
+	fieldIdx, ok := headerIndex[name]
+	if !ok || fieldIdx >= len(row) {
+		return NullValue(), fmt.Errorf(`Column "%v" does not exist`, name)
+	}
+	return CellValue(row[fieldIdx]), nil
 }
 
 // Handle validate input data based on AST expression
-func Eval(ast interface{}, row []string, headerIndex map[string]int) interface{} {
+func Eval(ast interface{}, row []string, headerIndex map[string]int) (Value, error) {
 
 	//If ast is instance of BetweenExpr
 	betweenExpr, isBetweenExpr := ast.(BetweenExpr)
@@ -164,141 +470,310 @@ func Eval(ast interface{}, row []string, headerIndex map[string]int) interface{}
 		return ComputeIn(inExpr, row, headerIndex)
 	}
 
+	//If ast is instance of LikeExpr
+	likeExpr, isLikeExpr := ast.(LikeExpr)
+	if isLikeExpr {
+		return ComputeLike(likeExpr, row, headerIndex)
+	}
+
+	//If ast is instance of AggregateExpr, its result is read from group row
+	aggregateExpr, isAggregateExpr := ast.(AggregateExpr)
+	if isAggregateExpr {
+		return ComputeAggregate(aggregateExpr, row, headerIndex)
+	}
+
+	//If ast is instance of GroupingExpr, its result is read from group row
+	groupingExpr, isGroupingExpr := ast.(GroupingExpr)
+	if isGroupingExpr {
+		return ComputeGrouping(groupingExpr, row, headerIndex)
+	}
+
+	//If ast is instance of WindowExpr, its result is read from source row
+	windowExpr, isWindowExpr := ast.(WindowExpr)
+	if isWindowExpr {
+		return ComputeWindow(windowExpr, row, headerIndex)
+	}
+
+	//If ast is instance of UnaryExpr
+	unaryExpr, isUnaryExpr := ast.(UnaryExpr)
+	if isUnaryExpr {
+		return ComputeUnary(unaryExpr, row, headerIndex)
+	}
+
+	//If ast is instance of IsNullExpr
+	isNullExpr, isIsNullExpr := ast.(IsNullExpr)
+	if isIsNullExpr {
+		return ComputeIsNull(isNullExpr, row, headerIndex)
+	}
+
+	//If ast is instance of CaseExpr
+	caseExpr, isCaseExpr := ast.(CaseExpr)
+	if isCaseExpr {
+		return ComputeCase(caseExpr, row, headerIndex)
+	}
+
+	//If ast is instance of FunctionExpr
+	functionExpr, isFunctionExpr := ast.(FunctionExpr)
+	if isFunctionExpr {
+		return ComputeFunction(functionExpr, row, headerIndex)
+	}
+
 	token, isToken := ast.(Token)
 	//If ast is token means the smallest unit to compute
 	if isToken {
-		if token.Type == TokenNumber {
-			number, _ := StringToInt(token.Value)
-			return number
-		}
 		if token.Type == TokenIdent {
-			fieldIdx := headerIndex[fmt.Sprintf("%v", token.Value)]
-			number, numberErr := StringToInt(row[fieldIdx])
-
-			//If data can perform number
-			if numberErr == nil {
-				return number
-			}
-			return Stringify(row[fieldIdx])
-		}
-		if token.Type == TokenString {
-			str := Stringify(token.Value)
-			return str
+			return ColumnValue(Stringify(token.Value), row, headerIndex)
 		}
+		return TokenToValue(token), nil
 	}
 
 	// If ast is expression => recurrive Eval()
-	binary, _ := ast.(BinaryExpr)
-	left := Eval(binary.Left, row, headerIndex)
-	right := Eval(binary.Right, row, headerIndex)
+	binary, isBinary := ast.(BinaryExpr)
+	if !isBinary {
+		return NullValue(), fmt.Errorf("Unsupported expression %T", ast)
+	}
+	left, err := Eval(binary.Left, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	right, err := Eval(binary.Right, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
 	op := binary.Op.Type
 	return Compute(left, op, right)
 }
 
-// Compute proxy to specific data type
-func Compute(left interface{}, op TokenType, right interface{}) int {
-	leftStr, isString := left.(string)
-	rightStr, _ := right.(string)
-	if isString {
-		return ComputeString(leftStr, op, rightStr)
-	}
-
-	leftNumber, _ := left.(int)
-	rightNumber, _ := right.(int)
-	return ComputeNumber(leftNumber, op, rightNumber)
-}
-
-// Handle compute for string
-func ComputeString(left string, op TokenType, right string) int {
+// Compute proxy to logical, arithmetic or comparison operator
+func Compute(left Value, op TokenType, right Value) (Value, error) {
 	switch op {
-	case TokenEqual:
+	case TokenAnd:
 		{
-			return BooleanToInt(left == right)
+			return ComputeAnd(left, right), nil
+		}
+	case TokenOr:
+		{
+			return ComputeOr(left, right), nil
+		}
+	case TokenPlus, TokenMinus, TokenStar, TokenSlash, TokenPercent:
+		{
+			return ArithmeticValues(left, op, right)
+		}
+	case TokenConcat:
+		{
+			return ConcatValues(left, right), nil
 		}
 	default:
 		{
-			return 0
+			return ComputeComparison(left, op, right)
 		}
 	}
 }
 
-// Handle compute for number (int or float)
-func ComputeNumber(left int, op TokenType, right int) int {
-	fmt.Println("ComputeNumber", left, right)
+// Three-valued AND: FALSE wins over NULL, NULL wins over TRUE
+func ComputeAnd(left, right Value) Value {
+	if (!left.IsNull() && !left.IsTrue()) || (!right.IsNull() && !right.IsTrue()) {
+		return BoolValue(false)
+	}
+	if left.IsNull() || right.IsNull() {
+		return NullValue()
+	}
+	return BoolValue(true)
+}
+
+// Three-valued OR: TRUE wins over NULL, NULL wins over FALSE
+func ComputeOr(left, right Value) Value {
+	if left.IsTrue() || right.IsTrue() {
+		return BoolValue(true)
+	}
+	if left.IsNull() || right.IsNull() {
+		return NullValue()
+	}
+	return BoolValue(false)
+}
+
+// Three-valued NOT: NOT NULL is NULL
+func ComputeNot(value Value) Value {
+	if value.IsNull() {
+		return NullValue()
+	}
+	return BoolValue(!value.IsTrue())
+}
+
+// Handle compute for comparison operators using value coercion rules.
+// Comparing with NULL gives NULL
+func ComputeComparison(left Value, op TokenType, right Value) (Value, error) {
+	cmp, isComparable := CompareValues(left, right)
+	if !isComparable {
+		return NullValue(), nil
+	}
 	switch op {
 	case TokenGreater:
 		{
-			return BooleanToInt(left > right)
+			return BoolValue(cmp > 0), nil
 		}
 	case TokenGreaterEqual:
 		{
-			return BooleanToInt(left >= right)
+			return BoolValue(cmp >= 0), nil
 		}
 	case TokenLess:
 		{
-			return BooleanToInt(left < right)
+			return BoolValue(cmp < 0), nil
 		}
 	case TokenLessEqual:
 		{
-			return BooleanToInt(left <= right)
+			return BoolValue(cmp <= 0), nil
 		}
 	case TokenEqual:
 		{
-			return BooleanToInt(left == right)
+			return BoolValue(cmp == 0), nil
 		}
 	case TokenNotEqual:
 		{
-			return BooleanToInt(left != right)
-		}
-	case TokenAnd:
-		{
-			return BooleanToInt(left == 1 && right == 1)
-		}
-	case TokenOr:
-		{
-			return BooleanToInt(left == 1 || right == 1)
+			return BoolValue(cmp != 0), nil
 		}
 	default:
 		{
-			return 0
+			return NullValue(), fmt.Errorf("Unsupported operator %v", op)
 		}
 	}
 }
 
 // Handle compute for BETWEEN expression
-func ComputeBetween(ast BetweenExpr, row []string, headerIndex map[string]int) int {
-	tokenExpr, isTokenExpr := ast.Expr.(Token)
-	tokenLower, isTokenLower := ast.Lower.(Token)
-	tokenUpper, isTokenUpper := ast.Upper.(Token)
-	if !isTokenExpr || !isTokenLower || !isTokenUpper {
-		return 0
+func ComputeBetween(ast BetweenExpr, row []string, headerIndex map[string]int) (Value, error) {
+	value, err := Eval(ast.Expr, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	lower, err := Eval(ast.Lower, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	upper, err := Eval(ast.Upper, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
 	}
-	fieldIdx := headerIndex[fmt.Sprintf("%v", tokenExpr.Value)]
-	number, _ := StringToInt(row[fieldIdx])
-	lowerNumber, _ := StringToInt(Stringify(tokenLower.Value))
-	upperNumber, _ := StringToInt(Stringify(tokenUpper.Value))
 
-	return BooleanToInt(number >= lowerNumber && number <= upperNumber)
+	isAboveLower, _ := ComputeComparison(value, TokenGreaterEqual, lower)
+	isBelowUpper, _ := ComputeComparison(value, TokenLessEqual, upper)
+	result := ComputeAnd(isAboveLower, isBelowUpper)
+	if ast.Not {
+		return ComputeNot(result), nil
+	}
+	return result, nil
 }
 
 // Handle compute for IN expression
-func ComputeIn(ast InExpr, row []string, headerIndex map[string]int) int {
-	fmt.Println("ComputeIn")
-	tokenExpr, isTokenExpr := ast.Expr.(Token)
+func ComputeIn(ast InExpr, row []string, headerIndex map[string]int) (Value, error) {
+	result, err := ComputeInList(ast, row, headerIndex)
+	if err != nil || !ast.Not {
+		return result, err
+	}
+	return ComputeNot(result), nil
+}
+
+func ComputeInList(ast InExpr, row []string, headerIndex map[string]int) (Value, error) {
 	collector, isCollector := ast.Collector.([]interface{})
-	if !isTokenExpr || !isCollector {
-		return 0
-	}
-	fieldIdx := headerIndex[fmt.Sprintf("%v", tokenExpr.Value)]
-	exprVal := row[fieldIdx]
-	fmt.Println("exprVal", exprVal)
-	isValid := 0
-	for _, val := range collector {
-		token, _ := val.(Token)
-		if Stringify(exprVal) == Stringify(token.Value) {
-			isValid = 1
-			break
+	if !isCollector {
+		return NullValue(), fmt.Errorf("Invalid IN list")
+	}
+	value, err := Eval(ast.Expr, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	if value.IsNull() {
+		return NullValue(), nil
+	}
+	// No match but a NULL in the list => result is unknown
+	isUnknown := false
+	for _, item := range collector {
+		itemValue, err := Eval(item, row, headerIndex)
+		if err != nil {
+			return NullValue(), err
+		}
+		cmp, isComparable := CompareValues(value, itemValue)
+		if !isComparable {
+			isUnknown = true
+			continue
+		}
+		if cmp == 0 {
+			return BoolValue(true), nil
 		}
 	}
-	return isValid
+	if isUnknown {
+		return NullValue(), nil
+	}
+	return BoolValue(false), nil
+}
+
+// Handle compute for prefix operator
+func ComputeUnary(ast UnaryExpr, row []string, headerIndex map[string]int) (Value, error) {
+	value, err := Eval(ast.Expr, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	switch ast.Op.Type {
+	case TokenNot:
+		{
+			return ComputeNot(value), nil
+		}
+	case TokenMinus:
+		{
+			return NegateValue(value)
+		}
+	case TokenPlus:
+		{
+			if value.IsNull() {
+				return value, nil
+			}
+			if numeric := CoerceNumeric(value); numeric.IsNumeric() {
+				return numeric, nil
+			}
+			return NullValue(), fmt.Errorf("Operator '+' can not be applied to %v", value.Kind)
+		}
+	default:
+		{
+			return NullValue(), fmt.Errorf("Unsupported operator %v", ast.Op.Type)
+		}
+	}
+}
+
+// Handle compute for IS [NOT] NULL expression
+func ComputeIsNull(ast IsNullExpr, row []string, headerIndex map[string]int) (Value, error) {
+	value, err := Eval(ast.Expr, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	return BoolValue(value.IsNull() != ast.Not), nil
+}
+
+// Handle compute for CASE expression: result of first matching WHEN, otherwise ELSE or NULL.
+// Simple CASE matches operand by equality, so NULL operand never matches
+func ComputeCase(ast CaseExpr, row []string, headerIndex map[string]int) (Value, error) {
+	operand := NullValue()
+	if ast.Operand != nil {
+		var err error
+		operand, err = Eval(ast.Operand, row, headerIndex)
+		if err != nil {
+			return NullValue(), err
+		}
+	}
+	for _, when := range ast.Whens {
+		condition, err := Eval(when.Condition, row, headerIndex)
+		if err != nil {
+			return NullValue(), err
+		}
+		isMatched := condition.IsTrue()
+		if ast.Operand != nil {
+			cmp, isComparable := CompareValues(operand, condition)
+			isMatched = isComparable && cmp == 0
+		}
+		if isMatched {
+			return Eval(when.Result, row, headerIndex)
+		}
+	}
+	if ast.Else == nil {
+		return NullValue(), nil
+	}
+	return Eval(ast.Else, row, headerIndex)
 }
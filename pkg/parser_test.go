@@ -0,0 +1,86 @@
+package pkg
+
+import "testing"
+
+func TestThreeValuedLogic(t *testing.T) {
+	values := map[string]Value{"true": BoolValue(true), "false": BoolValue(false), "null": NullValue()}
+	tests := []struct {
+		left, right string
+		and, or     string
+	}{
+		{"true", "true", "true", "true"},
+		{"true", "false", "false", "true"},
+		{"true", "null", "", "true"},
+		{"false", "false", "false", "false"},
+		{"false", "null", "false", ""},
+		{"null", "null", "", ""},
+	}
+	for _, test := range tests {
+		left, right := values[test.left], values[test.right]
+		for _, pair := range [][2]Value{{left, right}, {right, left}} {
+			if got := ComputeAnd(pair[0], pair[1]).String(); got != test.and {
+				t.Errorf("%v AND %v = %q, want %q", test.left, test.right, got, test.and)
+			}
+			if got := ComputeOr(pair[0], pair[1]).String(); got != test.or {
+				t.Errorf("%v OR %v = %q, want %q", test.left, test.right, got, test.or)
+			}
+		}
+	}
+	for name, want := range map[string]string{"true": "false", "false": "true", "null": ""} {
+		if got := ComputeNot(values[name]).String(); got != want {
+			t.Errorf("NOT %v = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExecuteNotAndParentheses(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			// Parentheses override AND binding tighter than OR
+			"SELECT id FROM sales WHERE (region = 'east' OR region = 'west') AND amount >= 20",
+			[][]string{{"id"}, {"2"}, {"3"}, {"6"}},
+		},
+		{
+			"SELECT id FROM sales WHERE region = 'east' OR region = 'west' AND amount >= 20",
+			[][]string{{"id"}, {"1"}, {"2"}, {"3"}, {"6"}},
+		},
+		{
+			// NOT binds tighter than AND, looser than comparison
+			"SELECT id FROM sales WHERE NOT region = 'east' AND NOT (amount > 20)",
+			[][]string{{"id"}, {"4"}, {"5"}},
+		},
+		{
+			"SELECT id FROM sales WHERE id NOT IN (1, 2, 3) AND amount NOT BETWEEN 10 AND 20",
+			[][]string{{"id"}, {"4"}, {"6"}, {"7"}},
+		},
+		{
+			"SELECT id FROM sales WHERE rep NOT LIKE 'A%' AND NOT score IS NULL",
+			[][]string{{"id"}, {"2"}, {"4"}, {"5"}, {"6"}, {"7"}},
+		},
+		{
+			"SELECT id, NOT (amount > 15) AS small FROM sales WHERE NOT NOT region = 'west'",
+			[][]string{{"id", "small"}, {"4", "true"}, {"5", "true"}, {"6", "false"}},
+		},
+		{
+			// NOT of NULL comparison is NULL, so row without score is dropped either way
+			"SELECT id FROM sales WHERE NOT score > 1",
+			[][]string{{"id"}, {"5"}, {"6"}},
+		},
+		{
+			// NOT IN list with NULL is never true
+			"SELECT id FROM sales WHERE score NOT IN (1, NULL)",
+			[][]string{{"id"}},
+		},
+		{
+			"SELECT id FROM sales WHERE ((id)) = 2",
+			[][]string{{"id"}, {"2"}},
+		},
+	})
+
+	if err := syntaxError(t, "SELECT id FROM sales WHERE (id = 2"); err.Pos != 34 || len(err.Expected) != 1 || err.Expected[0] != TokenRParen {
+		t.Errorf("unclosed parenthesis: got %v, want ')' expected at 34", err)
+	}
+	if err := syntaxError(t, "SELECT id FROM sales WHERE NOT"); err.Message != "expected expression, found end of query" {
+		t.Errorf("NOT without operand: got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Struct of LIKE, ILIKE and REGEXP expression
+type LikeExpr struct {
+	Expr    interface{}
+	Op      Token
+	Pattern interface{}
+	Escape  interface{}
+	Not     bool
+	Cache   *PatternCache
+}
+
+// Compiled patterns of one query, keyed by pattern and escape character.
+// Shared by every row so a pattern is compiled only once
+type PatternCache struct {
+	patterns map[string]*regexp.Regexp
+}
+
+func NewPatternCache() *PatternCache {
+	return &PatternCache{
+		patterns: map[string]*regexp.Regexp{},
+	}
+}
+
+// Default escape character of LIKE patterns when no ESCAPE clause is given
+const DefaultLikeEscape = `\`
+
+// Convert LIKE pattern into anchored regular expression: "%" matches any sequence, "_" any single character
+func LikeToRegexp(pattern string, escape string, isCaseInsensitive bool) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("(?s)")
+	if isCaseInsensitive {
+		builder.WriteString("(?i)")
+	}
+	builder.WriteString("^")
+
+	isEscaped := false
+	for _, char := range pattern {
+		switch {
+		case isEscaped:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+			isEscaped = false
+		case len(escape) > 0 && string(char) == escape:
+			isEscaped = true
+		case char == '%':
+			builder.WriteString(".*")
+		case char == '_':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	if isEscaped {
+		return "", fmt.Errorf("LIKE pattern %q must not end with escape character", pattern)
+	}
+	builder.WriteString("$")
+	return builder.String(), nil
+}
+
+// Get compiled regular expression of pattern, compile and remember it on first use
+func (cache *PatternCache) Compile(op TokenType, pattern string, escape string) (*regexp.Regexp, error) {
+	key := fmt.Sprintf("%d:%d:%v%v", op, len(escape), escape, pattern)
+	compiled, ok := cache.patterns[key]
+	if ok {
+		return compiled, nil
+	}
+
+	expression := pattern
+	if op != TokenRegexp {
+		var err error
+		expression, err = LikeToRegexp(pattern, escape, op == TokenILike)
+		if err != nil {
+			return nil, err
+		}
+	}
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regular expression %q: %v", pattern, err)
+	}
+	cache.patterns[key] = compiled
+	return compiled, nil
+}
+
+// Check escape character of LIKE pattern
+func ValidateEscape(escape string) error {
+	if utf8.RuneCountInString(escape) > 1 {
+		return fmt.Errorf("ESCAPE must be a single character, got %q", escape)
+	}
+	return nil
+}
+
+// Register binding power for LIKE, ILIKE and REGEXP operators
+func (p *Parser) RegisterLikeInfix(tokenType TokenType, lbp int) {
+	(*p).opTable[tokenType] = OpInfo{
+		lbp: lbp,
+		led: func(left interface{}) (interface{}, error) {
+			op := p.tokens[p.pointer-1]
+			pattern, err := p.ParseExpression(lbp)
+			if err != nil {
+				return nil, err
+			}
+			likeExpr := LikeExpr{
+				Expr:    left,
+				Op:      op,
+				Pattern: pattern,
+				Cache:   NewPatternCache(),
+			}
+
+			if p.current.Type == TokenEscape && op.Type != TokenRegexp {
+				escapeToken := p.current
+				p.Advance()
+				likeExpr.Escape, err = p.ParseExpression(lbp)
+				if err != nil {
+					return nil, err
+				}
+				if token, isToken := likeExpr.Escape.(Token); isToken && token.Type == TokenString {
+					if err := ValidateEscape(Stringify(token.Value)); err != nil {
+						return nil, NewSyntaxError(escapeToken, err.Error())
+					}
+				}
+			}
+
+			// Literal patterns are compiled once while parsing so mistakes are reported early
+			if token, isToken := pattern.(Token); isToken && token.Type == TokenString {
+				escape := DefaultLikeEscape
+				isLiteralEscape := likeExpr.Escape == nil
+				if escapeToken, isEscapeToken := likeExpr.Escape.(Token); isEscapeToken && escapeToken.Type == TokenString {
+					escape = Stringify(escapeToken.Value)
+					isLiteralEscape = true
+				}
+				if isLiteralEscape {
+					if _, err := likeExpr.Cache.Compile(op.Type, Stringify(token.Value), escape); err != nil {
+						return nil, NewSyntaxError(token, err.Error())
+					}
+				}
+			}
+			return likeExpr, nil
+		},
+	}
+}
+
+// Handle compute for LIKE, ILIKE and REGEXP expression
+func ComputeLike(ast LikeExpr, row []string, headerIndex map[string]int) (Value, error) {
+	value, err := Eval(ast.Expr, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	pattern, err := Eval(ast.Pattern, row, headerIndex)
+	if err != nil {
+		return NullValue(), err
+	}
+	escape := StringValue(DefaultLikeEscape)
+	if ast.Escape != nil {
+		escape, err = Eval(ast.Escape, row, headerIndex)
+		if err != nil {
+			return NullValue(), err
+		}
+		if err := ValidateEscape(escape.String()); err != nil {
+			return NullValue(), err
+		}
+	}
+	if value.IsNull() || pattern.IsNull() || escape.IsNull() {
+		return NullValue(), nil
+	}
+
+	compiled, err := ast.Cache.Compile(ast.Op.Type, pattern.String(), escape.String())
+	if err != nil {
+		return NullValue(), err
+	}
+	return BoolValue(compiled.MatchString(value.String()) != ast.Not), nil
+}
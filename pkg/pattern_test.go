@@ -0,0 +1,110 @@
+package pkg
+
+import "testing"
+
+func TestPatternCacheCompile(t *testing.T) {
+	tests := []struct {
+		op      TokenType
+		pattern string
+		escape  string
+		text    string
+		want    bool
+	}{
+		{TokenLike, "a%", DefaultLikeEscape, "abc", true},
+		{TokenLike, "a%", DefaultLikeEscape, "Abc", false},
+		{TokenLike, "a_c", DefaultLikeEscape, "abc", true},
+		{TokenLike, "a_c", DefaultLikeEscape, "abbc", false},
+		{TokenLike, "%", DefaultLikeEscape, "line\nbreak", true},
+		// Regular expression characters of LIKE pattern are literal
+		{TokenLike, "a.c", DefaultLikeEscape, "abc", false},
+		{TokenLike, "(a)+", DefaultLikeEscape, "(a)+", true},
+		{TokenLike, `50\%`, DefaultLikeEscape, "50%", true},
+		{TokenLike, `50\%`, DefaultLikeEscape, "500", false},
+		{TokenLike, "a!_c", "!", "a_c", true},
+		{TokenLike, "a!_c", "!", "abc", false},
+		{TokenLike, `a\%`, "", `a\bc`, true},
+		{TokenILike, "ä%", DefaultLikeEscape, "ÄBC", true},
+		// REGEXP matches anywhere unless anchored
+		{TokenRegexp, "b+", "", "abbc", true},
+		{TokenRegexp, "^b", "", "abc", false},
+	}
+	cache := NewPatternCache()
+	for _, test := range tests {
+		compiled, err := cache.Compile(test.op, test.pattern, test.escape)
+		if err != nil {
+			t.Errorf("%q: %v", test.pattern, err)
+			continue
+		}
+		if got := compiled.MatchString(test.text); got != test.want {
+			t.Errorf("%q escape %q matches %q = %v, want %v", test.pattern, test.escape, test.text, got, test.want)
+		}
+	}
+
+	// Pattern is compiled once per operator and escape
+	first, _ := cache.Compile(TokenLike, "a%", DefaultLikeEscape)
+	second, _ := cache.Compile(TokenLike, "a%", DefaultLikeEscape)
+	if first != second {
+		t.Error("same pattern compiled twice")
+	}
+	if ilike, _ := cache.Compile(TokenILike, "a%", DefaultLikeEscape); ilike == first {
+		t.Error("ILIKE reuses LIKE pattern")
+	}
+
+	for _, pattern := range []string{`a\`, "a!"} {
+		if _, err := cache.Compile(TokenLike, pattern, pattern[len(pattern)-1:]); err == nil {
+			t.Errorf("%q ending with escape: want error", pattern)
+		}
+	}
+	if _, err := cache.Compile(TokenRegexp, "(", ""); err == nil {
+		t.Error("invalid regular expression: want error")
+	}
+}
+
+func TestExecuteLike(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT id FROM sales WHERE rep LIKE '_e_'",
+			[][]string{{"id"}, {"2"}, {"5"}},
+		},
+		{
+			"SELECT id FROM sales WHERE rep LIKE 'a%'",
+			[][]string{{"id"}},
+		},
+		{
+			"SELECT id FROM sales WHERE rep ILIKE 'a%' OR rep ILIKE '%E'",
+			[][]string{{"id"}, {"1"}, {"3"}, {"5"}, {"7"}},
+		},
+		{
+			"SELECT id FROM sales WHERE rep REGEXP '^[A-C]' AND region ~ 'st$'",
+			[][]string{{"id"}, {"1"}, {"2"}, {"3"}, {"4"}, {"6"}},
+		},
+		{
+			// Pattern can be expression, NULL operand gives NULL
+			"SELECT id, rep LIKE 'A' || '%' AS a, score LIKE '1%' AS s FROM sales WHERE id < 4",
+			[][]string{{"id", "a", "s"}, {"1", "true", "true"}, {"2", "false", "false"}, {"3", "true", ""}},
+		},
+		{
+			`SELECT 'a%b' LIKE 'a!%b' ESCAPE '!', 'axb' LIKE 'a!%b' ESCAPE '!', 'a_b' LIKE 'a\_b', 'x' NOT REGEXP 'y' FROM sales LIMIT 1`,
+			[][]string{
+				{`'a%b' LIKE 'a!%b' ESCAPE '!'`, `'axb' LIKE 'a!%b' ESCAPE '!'`, `'a_b' LIKE 'a\_b'`, `'x' NOT REGEXP 'y'`},
+				{"true", "false", "true", "true"},
+			},
+		},
+	})
+
+	// Invalid literal patterns are reported at parse time, at the pattern
+	tests := []struct {
+		sql  string
+		pos  int
+		want string
+	}{
+		{`SELECT id FROM sales WHERE rep LIKE 'a\'`, 36, `LIKE pattern "a\\" must not end with escape character`},
+		{"SELECT id FROM sales WHERE rep REGEXP '('", 38, "Invalid regular expression \"(\": error parsing regexp: missing closing ): `(`"},
+		{"SELECT id FROM sales WHERE rep LIKE 'a' ESCAPE 'xy'", 40, `ESCAPE must be a single character, got "xy"`},
+	}
+	for _, test := range tests {
+		if err := syntaxError(t, test.sql); err.Message != test.want || err.Pos != test.pos {
+			t.Errorf("%v: got %q at %d, want %q at %d", test.sql, err.Message, err.Pos, test.want, test.pos)
+		}
+	}
+}
@@ -351,9 +351,13 @@ func (ql *CSVQL) Repl() {
 				ql.Execute()
 				duration := time.Since(start)
 				ql.Duration = float64(duration.Milliseconds())
-				ql.Render()
-
 				cmds = cmds[:0]
+
+				if ql.Error != nil {
+					fmt.Println(ql.Error)
+					continue
+				}
+				ql.Render()
 			}
 		}
 	}
@@ -3,7 +3,9 @@ package pkg
 import (
 	"fmt"
 	"slices"
-	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type TokenType int
@@ -13,6 +15,7 @@ type Token struct {
 	Value  any
 	Pos    int
 	EndPos int
+	Text   string // Source text of number literal, its Value is normalized
 }
 
 const (
@@ -57,8 +60,228 @@ const (
 	TokenRightJoin
 	TokenOn
 	TokenDot
+	TokenBoolean
+	TokenNull
+	TokenIs
+	TokenNot
+	TokenLike
+	TokenILike
+	TokenRegexp
+	TokenEscape
+	TokenPlus
+	TokenMinus
+	TokenSlash
+	TokenPercent
+	TokenConcat
+	TokenCase
+	TokenWhen
+	TokenThen
+	TokenElse
+	TokenEnd
+	TokenHaving
+	TokenDistinct
+	TokenOver
+	TokenPartitionBy
+	TokenRows
+	TokenRange
+	TokenPreceding
+	TokenFollowing
+	TokenCurrentRow
+	TokenUnboundedPreceding
+	TokenUnboundedFollowing
+	TokenRollup
+	TokenCube
+	TokenGroupingSets
+	TokenNullsFirst
+	TokenNullsLast
+	TokenOffset
+	TokenFetch
+	TokenRowsOnly
+	TokenFullJoin
+	TokenCrossJoin
 )
 
+var tokenNames = map[TokenType]string{
+	TokenEOF:          "end of query",
+	TokenIdent:        "identifier",
+	TokenSelect:       "SELECT",
+	TokenAs:           "AS",
+	TokenNumber:       "number",
+	TokenString:       "string",
+	TokenComma:        "','",
+	TokenStar:         "'*'",
+	TokenEqual:        "'='",
+	TokenNotEqual:     "'<>'",
+	TokenLess:         "'<'",
+	TokenGreater:      "'>'",
+	TokenLessEqual:    "'<='",
+	TokenGreaterEqual: "'>='",
+	TokenAnd:          "AND",
+	TokenOr:           "OR",
+	TokenFrom:         "FROM",
+	TokenWhere:        "WHERE",
+	TokenLimit:        "LIMIT",
+	TokenBetween:      "BETWEEN",
+	TokenIn:           "IN",
+	TokenLParen:       "'('",
+	TokenRParen:       "')'",
+	TokenOrderBy:      "ORDER BY",
+	TokenAsc:          "ASC",
+	TokenDesc:         "DESC",
+	TokenGroupBy:      "GROUP BY",
+	TokenSum:          "SUM",
+	TokenAverage:      "AVG",
+	TokenCount:        "COUNT",
+	TokenMax:          "MAX",
+	TokenMin:          "MIN",
+	TokenJoin:         "JOIN",
+	TokenLeftJoin:     "LEFT JOIN",
+	TokenRightJoin:    "RIGHT JOIN",
+	TokenOn:           "ON",
+	TokenDot:          "'.'",
+	TokenBoolean:      "boolean",
+	TokenNull:         "NULL",
+	TokenIs:           "IS",
+	TokenNot:          "NOT",
+	TokenLike:         "LIKE",
+	TokenILike:        "ILIKE",
+	TokenRegexp:       "REGEXP",
+	TokenEscape:       "ESCAPE",
+	TokenPlus:         "'+'",
+	TokenMinus:        "'-'",
+	TokenSlash:        "'/'",
+	TokenPercent:      "'%'",
+	TokenConcat:       "'||'",
+	TokenCase:         "CASE",
+	TokenWhen:         "WHEN",
+	TokenThen:         "THEN",
+	TokenElse:         "ELSE",
+	TokenEnd:          "END",
+	TokenHaving:       "HAVING",
+	TokenDistinct:     "DISTINCT",
+
+	// Window functions
+	TokenOver:               "OVER",
+	TokenPartitionBy:        "PARTITION BY",
+	TokenRows:               "ROWS",
+	TokenRange:              "RANGE",
+	TokenPreceding:          "PRECEDING",
+	TokenFollowing:          "FOLLOWING",
+	TokenCurrentRow:         "CURRENT ROW",
+	TokenUnboundedPreceding: "UNBOUNDED PRECEDING",
+	TokenUnboundedFollowing: "UNBOUNDED FOLLOWING",
+
+	// Grouping sets
+	TokenRollup:       "ROLLUP",
+	TokenCube:         "CUBE",
+	TokenGroupingSets: "GROUPING SETS",
+
+	// Ordering of NULL values
+	TokenNullsFirst: "NULLS FIRST",
+	TokenNullsLast:  "NULLS LAST",
+
+	// Paging
+	TokenOffset:   "OFFSET",
+	TokenFetch:    "FETCH FIRST",
+	TokenRowsOnly: "ROWS ONLY",
+
+	// Joins
+	TokenFullJoin:  "FULL JOIN",
+	TokenCrossJoin: "CROSS JOIN",
+}
+
+// Readable name of token type, used in error messages
+func (tokenType TokenType) String() string {
+	name, ok := tokenNames[tokenType]
+	if !ok {
+		return fmt.Sprintf("token(%d)", int(tokenType))
+	}
+	return name
+}
+
+// Keywords are matched case-insensitively against the upper-cased word
+var keywords = map[string]TokenType{
+	"SELECT":   TokenSelect,
+	"AS":       TokenAs,
+	"FROM":     TokenFrom,
+	"WHERE":    TokenWhere,
+	"AND":      TokenAnd,
+	"OR":       TokenOr,
+	"BETWEEN":  TokenBetween,
+	"IN":       TokenIn,
+	"ASC":      TokenAsc,
+	"DESC":     TokenDesc,
+	"LIMIT":    TokenLimit,
+	"SUM":      TokenSum,
+	"COUNT":    TokenCount,
+	"AVG":      TokenAverage,
+	"MAX":      TokenMax,
+	"MIN":      TokenMin,
+	"JOIN":     TokenJoin,
+	"ON":       TokenOn,
+	"TRUE":     TokenBoolean,
+	"FALSE":    TokenBoolean,
+	"NULL":     TokenNull,
+	"IS":       TokenIs,
+	"NOT":      TokenNot,
+	"LIKE":     TokenLike,
+	"ILIKE":    TokenILike,
+	"REGEXP":   TokenRegexp,
+	"ESCAPE":   TokenEscape,
+	"CASE":     TokenCase,
+	"WHEN":     TokenWhen,
+	"THEN":     TokenThen,
+	"ELSE":     TokenElse,
+	"END":      TokenEnd,
+	"HAVING":   TokenHaving,
+	"DISTINCT": TokenDistinct,
+
+	// Window functions
+	"OVER":      TokenOver,
+	"ROWS":      TokenRows,
+	"RANGE":     TokenRange,
+	"PRECEDING": TokenPreceding,
+	"FOLLOWING": TokenFollowing,
+
+	// Grouping sets
+	"ROLLUP": TokenRollup,
+	"CUBE":   TokenCube,
+
+	// Paging
+	"OFFSET": TokenOffset,
+}
+
+type MultiWordKeyword struct {
+	Words []string
+	Type  TokenType
+}
+
+// Keywords made of several words, separated by any whitespace.
+// Longer sequences go first so "LEFT OUTER JOIN" wins over "LEFT JOIN"
+var multiWordKeywords = []MultiWordKeyword{
+	{Words: []string{"ORDER", "BY"}, Type: TokenOrderBy},
+	{Words: []string{"GROUP", "BY"}, Type: TokenGroupBy},
+	{Words: []string{"LEFT", "OUTER", "JOIN"}, Type: TokenLeftJoin},
+	{Words: []string{"LEFT", "JOIN"}, Type: TokenLeftJoin},
+	{Words: []string{"RIGHT", "OUTER", "JOIN"}, Type: TokenRightJoin},
+	{Words: []string{"RIGHT", "JOIN"}, Type: TokenRightJoin},
+	{Words: []string{"FULL", "OUTER", "JOIN"}, Type: TokenFullJoin},
+	{Words: []string{"FULL", "JOIN"}, Type: TokenFullJoin},
+	{Words: []string{"INNER", "JOIN"}, Type: TokenJoin},
+	{Words: []string{"CROSS", "JOIN"}, Type: TokenCrossJoin},
+	{Words: []string{"PARTITION", "BY"}, Type: TokenPartitionBy},
+	{Words: []string{"CURRENT", "ROW"}, Type: TokenCurrentRow},
+	{Words: []string{"UNBOUNDED", "PRECEDING"}, Type: TokenUnboundedPreceding},
+	{Words: []string{"UNBOUNDED", "FOLLOWING"}, Type: TokenUnboundedFollowing},
+	{Words: []string{"GROUPING", "SETS"}, Type: TokenGroupingSets},
+	{Words: []string{"NULLS", "FIRST"}, Type: TokenNullsFirst},
+	{Words: []string{"NULLS", "LAST"}, Type: TokenNullsLast},
+	{Words: []string{"FETCH", "FIRST"}, Type: TokenFetch},
+	{Words: []string{"FETCH", "NEXT"}, Type: TokenFetch},
+	{Words: []string{"ROWS", "ONLY"}, Type: TokenRowsOnly},
+	{Words: []string{"ROW", "ONLY"}, Type: TokenRowsOnly},
+}
+
 func IsNumber(code int) bool {
 	return code >= 48 && code <= 57
 }
@@ -72,6 +295,19 @@ func IsOperator(char string) bool {
 	return slices.Contains(operators, char)
 }
 
+func IsArithmeticOperator(char string) bool {
+	operators := []string{"+", "-", "/", "%"}
+	return slices.Contains(operators, char)
+}
+
+func IsPipe(char string) bool {
+	return char == "|"
+}
+
+func IsTilde(char string) bool {
+	return char == "~"
+}
+
 func IsComma(char string) bool {
 	return char == ","
 }
@@ -80,16 +316,18 @@ func IsDot(char string) bool {
 	return char == "."
 }
 
+// Any Unicode whitespace, plus the byte order mark editors like to prepend
 func IsWhiteSpace(char string) bool {
-	return char == " "
+	r, _ := utf8.DecodeRuneInString(char)
+	return unicode.IsSpace(r) || r == '\uFEFF'
 }
 
 func IsNewLine(char string) bool {
-	return char == `\n`
+	return char == "\n" || char == "\r"
 }
 
 func IsTab(char string) bool {
-	return char == `\t`
+	return char == "\t"
 }
 
 func IsSkipParsing(char string) bool {
@@ -100,10 +338,16 @@ func IsHyphen(char string) bool {
 	return char == "-"
 }
 
+func IsSemicolon(char string) bool {
+	return char == ";"
+}
+
 func IsIdentifier(code int) bool {
-	isUppercase := code >= 65 && code <= 90
-	isLowercase := code >= 97 && code <= 122
-	return isUppercase || isLowercase
+	return unicode.IsLetter(rune(code)) || IsUnderscore(string(rune(code)))
+}
+
+func IsIdentifierPart(code int) bool {
+	return IsIdentifier(code) || unicode.IsDigit(rune(code))
 }
 
 func IsStar(char string) bool {
@@ -122,42 +366,6 @@ func IsUnderscore(char string) bool {
 	return char == "_"
 }
 
-func IsOrderByStart(str string) bool {
-	return str == "ORDER"
-}
-
-func IsOrderBy(str string) bool {
-	return str == "ORDER BY"
-}
-
-func IsGroupByStart(str string) bool {
-	return str == "GROUP"
-}
-
-func IsGroupBy(str string) bool {
-	return str == "GROUP BY"
-}
-
-func IsLeftJoinStart(str string) bool {
-	return str == "LEFT"
-}
-
-func IsLeftJoin(str string) bool {
-	return str == "LEFT JOIN"
-}
-
-func IsRightJoinStart(str string) bool {
-	return str == "RIGHT"
-}
-
-func IsRightJoin(str string) bool {
-	return str == "RIGHT JOIN"
-}
-
-func IsSum(str string) bool {
-	return str == "SUM"
-}
-
 func IsAggregateFn(token Column) bool {
 	aggregateFnType := []TokenType{
 		TokenSum, TokenCount, TokenMax, TokenMin, TokenAverage,
@@ -169,199 +377,173 @@ func IsEOF(token Token) bool {
 	return token.Type == TokenEOF
 }
 
-func ParseIdentifier(startIdx int, sql string) (TokenType, string, int) {
-	byteArr := []byte{}
+// Read one word of identifier characters, return the word and index after it
+func ParseWord(startIdx int, sql string) (string, int) {
 	endIdx := startIdx
-	isHaveUnderscore := false
-	for i := startIdx; i < len(sql); i++ {
-		char := sql[i]
-		code, _ := strconv.Atoi(
-			fmt.Sprintf("%d", char),
-		)
-
-		if IsHyphen(string(char)) {
-			panic(fmt.Sprintf("Syntax error at position %v", i))
+	for endIdx < len(sql) {
+		char, size := utf8.DecodeRuneInString(sql[endIdx:])
+		if !IsIdentifierPart(int(char)) {
+			break
 		}
+		endIdx += size
+	}
+	return sql[startIdx:endIdx], endIdx
+}
 
-		if IsUnderscore(string(char)) {
-			isHaveUnderscore = true
+// Skip whitespace, return index of next non-whitespace character
+func SkipWhiteSpace(startIdx int, sql string) int {
+	endIdx := startIdx
+	for endIdx < len(sql) {
+		char, size := utf8.DecodeRuneInString(sql[endIdx:])
+		if !IsSkipParsing(string(char)) {
+			break
 		}
+		endIdx += size
+	}
+	return endIdx
+}
 
-		if IsOrderByStart(string(byteArr)) {
-			if IsOrderBy(string(byteArr)) {
-				break
-			}
-		} else if IsGroupByStart(string(byteArr)) {
-			if IsGroupBy(string(byteArr)) {
-				break
-			}
-		} else if IsLeftJoinStart(string(byteArr)) {
-			if IsLeftJoin(string(byteArr)) {
+// Try to match a multi-word keyword starting with the word already read
+func ParseMultiWordKeyword(word string, wordEndIdx int, sql string) (TokenType, string, int, bool) {
+	for _, keyword := range multiWordKeywords {
+		if keyword.Words[0] != word {
+			continue
+		}
+		endIdx := wordEndIdx
+		isMatched := true
+		for _, nextWord := range keyword.Words[1:] {
+			nextIdx := SkipWhiteSpace(endIdx, sql)
+			if nextIdx == endIdx {
+				isMatched = false
 				break
 			}
-		} else if IsRightJoinStart(string(byteArr)) {
-			if IsRightJoin(string(byteArr)) {
+			value, nextEndIdx := ParseWord(nextIdx, sql)
+			if strings.ToUpper(value) != nextWord {
+				isMatched = false
 				break
 			}
-		} else if !IsIdentifier(code) {
-			// If have underscore => Only stop parse when meet "," or whitespace
-			if isHaveUnderscore {
-				if IsComma(string(char)) || IsSkipParsing(string(char)) || IsRightParen(string(char)) || IsDot(string(char)) {
-					break
-				}
-			} else {
-				if IsComma(string(char)) || IsSkipParsing(string(char)) || IsDot(string(char)) {
-					break
-				}
-
-				if !IsNumber(code) {
-					break
-				}
-			}
+			endIdx = nextEndIdx
 		}
+		if isMatched {
+			return keyword.Type, strings.Join(keyword.Words, " "), endIdx, true
+		}
+	}
+	return TokenIdent, word, wordEndIdx, false
+}
 
-		byteArr = append(byteArr, char)
+// Parse identifier or keyword. Keywords are case-insensitive and returned in upper case,
+// identifiers keep their original case
+func ParseIdentifier(startIdx int, sql string) (TokenType, string, int, error) {
+	identifier, endIdx := ParseWord(startIdx, sql)
+	word := strings.ToUpper(identifier)
+
+	tokenType, value, keywordEndIdx, isMultiWord := ParseMultiWordKeyword(word, endIdx, sql)
+	if isMultiWord {
+		return tokenType, value, keywordEndIdx - 1, nil
+	}
+
+	tokenType, isKeyword := keywords[word]
+	if isKeyword {
+		return tokenType, word, endIdx - 1, nil
+	}
+	return TokenIdent, identifier, endIdx - 1, nil
+}
+
+// Collect consecutive digits, return index after the last digit
+func ParseDigits(startIdx int, sql string) int {
+	endIdx := startIdx
+	for endIdx < len(sql) && IsNumber(int(sql[endIdx])) {
 		endIdx++
 	}
-	identifier := string(byteArr)
-	endIdx--
+	return endIdx
+}
 
-	switch identifier {
-	case "SELECT":
-		{
-			return TokenSelect, string(byteArr), endIdx
-		}
-	case "AS":
-		{
-			return TokenAs, string(byteArr), endIdx
-		}
-	case "FROM":
-		{
-			return TokenFrom, string(byteArr), endIdx
-		}
-	case "WHERE":
-		{
-			return TokenWhere, string(byteArr), endIdx
-		}
-	case "AND":
-		{
-			return TokenAnd, string(byteArr), endIdx
-		}
-	case "BETWEEN":
-		{
-			return TokenBetween, string(byteArr), endIdx
-		}
-	case "IN":
-		{
-			return TokenIn, string(byteArr), endIdx
-		}
-	case "ORDER BY":
-		{
-			return TokenOrderBy, string(byteArr), endIdx
-		}
-	case "GROUP BY":
-		{
-			return TokenGroupBy, string(byteArr), endIdx
-		}
-	case "ASC":
-		{
-			return TokenAsc, string(byteArr), endIdx
-		}
-	case "DESC":
-		{
-			return TokenDesc, string(byteArr), endIdx
-		}
-	case "OR":
-		{
-			return TokenOr, string(byteArr), endIdx
-		}
-	case "LIMIT":
-		{
-			return TokenLimit, string(byteArr), endIdx
-		}
-	case "SUM":
-		{
-			return TokenSum, string(byteArr), endIdx
-		}
-	case "COUNT":
-		{
-			return TokenCount, string(byteArr), endIdx
-		}
-	case "AVG":
-		{
-			return TokenAverage, string(byteArr), endIdx
-		}
-	case "MAX":
-		{
-			return TokenMax, string(byteArr), endIdx
-		}
-	case "MIN":
-		{
-			return TokenMin, string(byteArr), endIdx
-		}
-	case "JOIN":
-		{
-			return TokenJoin, string(byteArr), endIdx
-		}
-	case "LEFT JOIN":
-		{
-			return TokenLeftJoin, string(byteArr), endIdx
-		}
-	case "RIGHT JOIN":
-		{
-			return TokenRightJoin, string(byteArr), endIdx
+// Parse number literal: integer, decimal (1.5, .5), scientific (1.5e3, 2E-4), optionally signed.
+// Integers become int values, decimals exact decimal values and scientific notation float values
+func ParseNumber(startIdx int, sql string) (Value, int, error) {
+	endIdx := startIdx
+	if IsHyphen(string(sql[endIdx])) {
+		endIdx++
+	}
+	endIdx = ParseDigits(endIdx, sql)
+
+	if endIdx+1 < len(sql) && IsDot(string(sql[endIdx])) && IsNumber(int(sql[endIdx+1])) {
+		endIdx = ParseDigits(endIdx+1, sql)
+	}
+
+	if endIdx < len(sql) && (sql[endIdx] == 'e' || sql[endIdx] == 'E') {
+		exponentIdx := endIdx + 1
+		if exponentIdx < len(sql) && (sql[exponentIdx] == '+' || sql[exponentIdx] == '-') {
+			exponentIdx++
 		}
-	case "ON":
-		{
-			return TokenOn, string(byteArr), endIdx
+		if exponentIdx < len(sql) && IsNumber(int(sql[exponentIdx])) {
+			endIdx = ParseDigits(exponentIdx, sql)
 		}
-	default:
-		{
+	}
 
-			return TokenIdent, string(byteArr), endIdx
-		}
+	literal := sql[startIdx:endIdx]
+	endIdx--
+	number, ok := ParseNumberValue(literal)
+	if !ok {
+		return Value{}, endIdx, NewSyntaxError(Token{
+			Type:   TokenNumber,
+			Value:  literal,
+			Pos:    startIdx,
+			EndPos: endIdx,
+		}, fmt.Sprintf("invalid number %v", literal))
 	}
+	return number, endIdx, nil
 }
 
-func ParseNumber(startIdx int, sql string) (int, int) {
-	numberArr := []byte{}
-	endIdx := startIdx
-	for i := startIdx; i < len(sql); i++ {
-		char := sql[i]
-		code, _ := strconv.Atoi(
-			fmt.Sprintf("%d", char),
-		)
-		if !IsNumber(code) || IsSkipParsing(string(char)) {
-			break
-		}
-		numberArr = append(numberArr, char)
-		endIdx += 1
+// Check whether character at index starts a number literal.
+// A hyphen only starts a negative number when it can't be a binary minus, i.e. after an operator or keyword
+func IsNumberStart(idx int, sql string, tokens []Token) bool {
+	char := string(sql[idx])
+	if IsNumber(int(sql[idx])) {
+		return true
+	}
+	isNextDigit := idx+1 < len(sql) && IsNumber(int(sql[idx+1]))
+	isNextDotDigit := idx+2 < len(sql) && IsDot(string(sql[idx+1])) && IsNumber(int(sql[idx+2]))
+	if IsDot(char) {
+		return isNextDigit && !IsOperand(tokens)
+	}
+	if IsHyphen(char) {
+		return (isNextDigit || isNextDotDigit) && !IsOperand(tokens)
+	}
+	return false
+}
 
+// Check whether last token ends an operand
+func IsOperand(tokens []Token) bool {
+	if len(tokens) == 0 {
+		return false
 	}
-	endIdx--
-	number, _ := strconv.Atoi(
-		string(numberArr),
-	)
-	return number, endIdx
+	operandTypes := []TokenType{TokenIdent, TokenNumber, TokenString, TokenBoolean, TokenNull, TokenRParen, TokenEnd}
+	return slices.Contains(operandTypes, tokens[len(tokens)-1].Type)
 }
 
-func ParseString(startIdx int, sql string) (string, int) {
+func ParseString(startIdx int, sql string) (string, int, error) {
 	strBytes := []byte{}
 	endIdx := startIdx
 	for i := startIdx + 1; i < len(sql); i++ {
 		char := sql[i]
 		if IsSingleQuote(string(char)) {
 			endIdx += 1
-			break
+			return string(strBytes), endIdx, nil
 		}
 		strBytes = append(strBytes, char)
 		endIdx += 1
 
 	}
-	return string(strBytes), endIdx
+	return string(strBytes), endIdx, NewSyntaxError(Token{
+		Type:   TokenString,
+		Value:  string(strBytes),
+		Pos:    startIdx,
+		EndPos: endIdx,
+	}, "unterminated string literal")
 }
 
-func ParseOperator(startIdx int, sql string) (TokenType, string, int) {
+func ParseOperator(startIdx int, sql string) (TokenType, string, int, error) {
 	byteArr := []byte{}
 	endIdx := startIdx
 	for i := startIdx; i < len(sql); i++ {
@@ -379,28 +561,36 @@ func ParseOperator(startIdx int, sql string) (TokenType, string, int) {
 	switch identifier {
 	case ">":
 		{
-			return TokenGreater, string(byteArr), endIdx
+			return TokenGreater, string(byteArr), endIdx, nil
 		}
 	case ">=":
 		{
-			return TokenGreaterEqual, string(byteArr), endIdx
+			return TokenGreaterEqual, string(byteArr), endIdx, nil
 		}
 	case "<":
 		{
-			return TokenLess, string(byteArr), endIdx
+			return TokenLess, string(byteArr), endIdx, nil
 		}
 	case "<=":
 		{
-			return TokenLessEqual, string(byteArr), endIdx
+			return TokenLessEqual, string(byteArr), endIdx, nil
+		}
+	case "<>", "!=":
+		{
+			return TokenNotEqual, string(byteArr), endIdx, nil
 		}
-	case "<>":
+	case "=":
 		{
-			return TokenNotEqual, string(byteArr), endIdx
+			return TokenEqual, string(byteArr), endIdx, nil
 		}
 	default:
 		{
-
-			return TokenEqual, string(byteArr), endIdx
+			return TokenEqual, string(byteArr), endIdx, NewSyntaxError(Token{
+				Type:   TokenEqual,
+				Value:  identifier,
+				Pos:    startIdx,
+				EndPos: endIdx,
+			}, fmt.Sprintf("unknown operator %q", identifier))
 		}
 	}
 }
@@ -410,23 +600,30 @@ func (ql *CSVQL) Tokenizer() {
 	tokens := []Token{}
 	pointer := 0
 	for pointer < len(sql) {
-		char := sql[pointer]
-		code, _ := strconv.Atoi(
-			fmt.Sprintf("%d", char),
-		)
+		char, size := utf8.DecodeRuneInString(sql[pointer:])
+		code := int(char)
 
 		// If character is a number => parsing to get the whole number
-		if IsNumber(code) {
-			value, endIdx := ParseNumber(pointer, sql)
+		if IsNumberStart(pointer, sql, tokens) {
+			value, endIdx, err := ParseNumber(pointer, sql)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
 			tokens = append(tokens, Token{
 				Type:   TokenNumber,
 				Value:  value,
 				Pos:    pointer,
 				EndPos: endIdx,
+				Text:   sql[pointer : endIdx+1],
 			})
 			pointer = endIdx
 		} else if IsSingleQuote(string(char)) { // If character is single quote => Parsing to get whole string within 2 single quotes
-			value, endIdx := ParseString(pointer, sql)
+			value, endIdx, err := ParseString(pointer, sql)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
 			tokens = append(tokens, Token{
 				Type:   TokenString,
 				Value:  value,
@@ -435,7 +632,11 @@ func (ql *CSVQL) Tokenizer() {
 			})
 			pointer = endIdx
 		} else if IsIdentifier(code) { // If character is a string => Parsing to get the whole identifier
-			tokenType, value, endIdx := ParseIdentifier(pointer, sql)
+			tokenType, value, endIdx, err := ParseIdentifier(pointer, sql)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
 			tokens = append(tokens, Token{
 				Type:   tokenType,
 				Value:  value,
@@ -445,19 +646,25 @@ func (ql *CSVQL) Tokenizer() {
 			pointer = endIdx
 		} else if IsComma(string(char)) { // If character is a comma => Parsing to get comma
 			tokens = append(tokens, Token{
-				Type:  TokenComma,
-				Value: string(char),
-				Pos:   pointer,
+				Type:   TokenComma,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
 			})
 		} else if IsDot(string(char)) {
 			tokens = append(tokens, Token{
-				Type:  TokenDot,
-				Value: string(char),
-				Pos:   pointer,
+				Type:   TokenDot,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
 			})
 
 		} else if IsOperator(string(char)) { // If charater is a operator => Parsing to get operator
-			tokenType, value, endIdx := ParseOperator(pointer, sql)
+			tokenType, value, endIdx, err := ParseOperator(pointer, sql)
+			if err != nil {
+				ql.SetError(err)
+				return
+			}
 			tokens = append(tokens, Token{
 				Type:   tokenType,
 				Value:  value,
@@ -466,30 +673,147 @@ func (ql *CSVQL) Tokenizer() {
 			})
 			pointer = endIdx
 
+		} else if IsArithmeticOperator(string(char)) {
+			tokenTypes := map[string]TokenType{"+": TokenPlus, "-": TokenMinus, "/": TokenSlash, "%": TokenPercent}
+			tokens = append(tokens, Token{
+				Type:   tokenTypes[string(char)],
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
+			})
+		} else if IsPipe(string(char)) && pointer+1 < len(sql) && IsPipe(string(sql[pointer+1])) { // "||" concatenates strings
+			tokens = append(tokens, Token{
+				Type:   TokenConcat,
+				Value:  "||",
+				Pos:    pointer,
+				EndPos: pointer + 1,
+			})
+			pointer++
+		} else if IsTilde(string(char)) { // "~" is shorthand of REGEXP
+			tokens = append(tokens, Token{
+				Type:   TokenRegexp,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
+			})
 		} else if IsStar(string(char)) { // If character is "*" => Parsing to get "*"
 			tokens = append(tokens, Token{
-				Type:  TokenStar,
-				Value: string(char),
-				Pos:   pointer,
+				Type:   TokenStar,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
 			})
 		} else if IsLeftParen(string(char)) {
 			tokens = append(tokens, Token{
-				Type:  TokenLParen,
-				Value: string(char),
-				Pos:   pointer,
+				Type:   TokenLParen,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
 			})
 		} else if IsRightParen(string(char)) {
 			tokens = append(tokens, Token{
-				Type:  TokenRParen,
-				Value: string(char),
-				Pos:   pointer,
+				Type:   TokenRParen,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer,
 			})
+		} else if IsSkipParsing(string(char)) || IsSemicolon(string(char)) {
+			pointer += size - 1
+		} else {
+			ql.SetError(NewSyntaxError(Token{
+				Type:   TokenIdent,
+				Value:  string(char),
+				Pos:    pointer,
+				EndPos: pointer + size - 1,
+			}, fmt.Sprintf("unexpected character %q", string(char))))
+			return
 		}
 		pointer++
 	}
 	tokens = append(tokens, Token{
-		Type:  TokenEOF,
-		Value: nil,
+		Type:   TokenEOF,
+		Value:  nil,
+		Pos:    len(sql),
+		EndPos: len(sql),
 	})
+	ResolveContextualKeywords(tokens, sql)
 	ql.Tokens = tokens
 }
+
+// Handle words that are keywords only within their clause, elsewhere they name columns, e.g. SELECT end FROM t.
+// END closes CASE only after an operand, so CASE WHEN end > 0 THEN 1 END reads the first END as column.
+// OVER starts window only between call and "(", frame words are keywords only where frame is expected within OVER.
+// OFFSET starts clause after LIMIT, or after operand or sort order when row count or end of query follows,
+// elsewhere it names column or alias, e.g. SELECT offset FROM t or FROM t offset WHERE ...
+func ResolveContextualKeywords(tokens []Token, sql string) {
+	caseDepth := 0
+	isAfterLimit := false
+	parenDepth := 0
+	overDepth := -1 // depth outside of parentheses of OVER clause, -1 outside of OVER clause
+	for i, token := range tokens {
+		previous, next := Token{}, Token{}
+		if i > 0 {
+			previous = tokens[i-1]
+		}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		isKeyword := true
+		switch token.Type {
+		case TokenLParen:
+			{
+				parenDepth++
+			}
+		case TokenRParen:
+			{
+				parenDepth--
+				if parenDepth == overDepth {
+					overDepth = -1
+				}
+			}
+		case TokenOver:
+			{
+				isKeyword = previous.Type == TokenRParen && next.Type == TokenLParen
+				if isKeyword {
+					overDepth = parenDepth
+				}
+			}
+		case TokenRows, TokenRange:
+			{
+				isKeyword = overDepth >= 0 && slices.Contains([]TokenType{TokenBetween, TokenCurrentRow, TokenUnboundedPreceding, TokenNumber}, next.Type)
+			}
+		case TokenPreceding, TokenFollowing:
+			{
+				isKeyword = overDepth >= 0 && previous.Type == TokenNumber
+			}
+		case TokenLimit:
+			{
+				isAfterLimit = true
+			}
+		case TokenOffset:
+			{
+				isClauseEnd := IsOperand(tokens[:i]) || slices.Contains([]TokenType{TokenAsc, TokenDesc, TokenNullsFirst, TokenNullsLast}, previous.Type)
+				isKeyword = isAfterLimit || previous.Type != TokenDot && isClauseEnd && (next.Type == TokenNumber || next.Type == TokenEOF)
+			}
+		case TokenCase:
+			{
+				caseDepth++
+			}
+		case TokenEnd:
+			{
+				isKeyword = caseDepth > 0 && IsOperand(tokens[:i]) && previous.Type != TokenDot
+				if isKeyword {
+					caseDepth--
+				}
+			}
+		}
+		if !isKeyword {
+			tokens[i] = Token{
+				Type:   TokenIdent,
+				Value:  sql[token.Pos : token.EndPos+1],
+				Pos:    token.Pos,
+				EndPos: token.EndPos,
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tokenize query, fail test on error
+func tokenize(t *testing.T, sql string) []Token {
+	t.Helper()
+	ql := CSVQL{Sql: sql}
+	ql.Tokenizer()
+	if ql.Error != nil {
+		t.Fatalf("%q: %v", sql, ql.Error)
+	}
+	return ql.Tokens
+}
+
+func tokenTypes(tokens []Token) []TokenType {
+	types := []TokenType{}
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	return types
+}
+
+func TestTokenizerKeywordCase(t *testing.T) {
+	want := []TokenType{TokenSelect, TokenIdent, TokenFrom, TokenIdent, TokenWhere, TokenIdent, TokenIs, TokenNot, TokenNull, TokenEOF}
+	for _, sql := range []string{
+		"SELECT Name FROM Staff WHERE Age IS NOT NULL",
+		"select Name from Staff where Age is not null",
+		"SeLeCt Name FrOm Staff wHeRe Age Is NoT nUlL",
+	} {
+		tokens := tokenize(t, sql)
+		if got := tokenTypes(tokens); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %v, want %v", sql, got, want)
+			continue
+		}
+		// Identifiers keep their case
+		if tokens[1].Value != "Name" || tokens[3].Value != "Staff" {
+			t.Errorf("%q: got identifiers %v, %v, want Name, Staff", sql, tokens[1].Value, tokens[3].Value)
+		}
+	}
+}
+
+func TestTokenizerMultiWordKeywords(t *testing.T) {
+	tests := []struct {
+		sql   string
+		want  []TokenType
+		value string
+	}{
+		{"ORDER BY", []TokenType{TokenOrderBy, TokenEOF}, "ORDER BY"},
+		{"order\t\tby", []TokenType{TokenOrderBy, TokenEOF}, "ORDER BY"},
+		{"Group\n  By", []TokenType{TokenGroupBy, TokenEOF}, "GROUP BY"},
+		{"LEFT OUTER\n JOIN", []TokenType{TokenLeftJoin, TokenEOF}, "LEFT OUTER JOIN"},
+		{"left\r\njoin", []TokenType{TokenLeftJoin, TokenEOF}, "LEFT JOIN"},
+		{"FULL\u00a0OUTER\u2003JOIN", []TokenType{TokenFullJoin, TokenEOF}, "FULL OUTER JOIN"},
+		{"cross join", []TokenType{TokenCrossJoin, TokenEOF}, "CROSS JOIN"},
+		// Words of keyword are separate identifiers when rest of keyword is missing
+		{"order x", []TokenType{TokenIdent, TokenIdent, TokenEOF}, "order"},
+	}
+	for _, test := range tests {
+		tokens := tokenize(t, test.sql)
+		if got := tokenTypes(tokens); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.sql, got, test.want)
+			continue
+		}
+		if tokens[0].Value != test.value || tokens[0].Pos != 0 {
+			t.Errorf("%q: got %q at %d, want %q at 0", test.sql, tokens[0].Value, tokens[0].Pos, test.value)
+		}
+	}
+}
+
+func TestTokenizerWhitespace(t *testing.T) {
+	want := []TokenType{TokenSelect, TokenIdent, TokenComma, TokenIdent, TokenFrom, TokenIdent, TokenEOF}
+	for _, sql := range []string{
+		"SELECT a, b FROM t",
+		"SELECT\ta,\tb\nFROM\r\nt\n",
+		"\u00a0SELECT\u2003a,\u3000b\u2028FROM\vt\f",
+	} {
+		if got := tokenTypes(tokenize(t, sql)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestTokenizerKeywordColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []TokenType
+	}{
+		{
+			"SELECT end, rows, over, offset FROM t",
+			[]TokenType{TokenSelect, TokenIdent, TokenComma, TokenIdent, TokenComma, TokenIdent, TokenComma, TokenIdent, TokenFrom, TokenIdent, TokenEOF},
+		},
+		{
+			"SELECT t.end FROM t WHERE end > 1",
+			[]TokenType{TokenSelect, TokenIdent, TokenDot, TokenIdent, TokenFrom, TokenIdent, TokenWhere, TokenIdent, TokenGreater, TokenNumber, TokenEOF},
+		},
+		{
+			"CASE WHEN end > 1 THEN end END",
+			[]TokenType{TokenCase, TokenWhen, TokenIdent, TokenGreater, TokenNumber, TokenThen, TokenIdent, TokenEnd, TokenEOF},
+		},
+		{
+			"SUM(rows) OVER (ORDER BY over ROWS 1 PRECEDING)",
+			[]TokenType{TokenSum, TokenLParen, TokenIdent, TokenRParen, TokenOver, TokenLParen, TokenOrderBy, TokenIdent, TokenRows, TokenNumber, TokenPreceding, TokenRParen, TokenEOF},
+		},
+		{
+			"ORDER BY offset LIMIT 1 OFFSET 2",
+			[]TokenType{TokenOrderBy, TokenIdent, TokenLimit, TokenNumber, TokenOffset, TokenNumber, TokenEOF},
+		},
+	}
+	for _, test := range tests {
+		if got := tokenTypes(tokenize(t, test.sql)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q:\n got %v\nwant %v", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestTokenizerNumbers(t *testing.T) {
+	tests := []struct {
+		literal string
+		kind    ValueKind
+		want    string
+	}{
+		{"42", KindInt, "42"},
+		{"007", KindInt, "7"},
+		{"-3", KindInt, "-3"},
+		{"1.5", KindDecimal, "1.5"},
+		{".5", KindDecimal, "0.5"},
+		{"-.25", KindDecimal, "-0.25"},
+		{"99999999999999999999", KindDecimal, "99999999999999999999"},
+		{"1.5e3", KindFloat, "1500"},
+		{"2E-4", KindFloat, "0.0002"},
+		{"-1e+2", KindFloat, "-100"},
+	}
+	for _, test := range tests {
+		// Literal follows comma, so hyphen is sign rather than minus
+		tokens := tokenize(t, "SELECT 1, "+test.literal)
+		if got := tokenTypes(tokens); !reflect.DeepEqual(got, []TokenType{TokenSelect, TokenNumber, TokenComma, TokenNumber, TokenEOF}) {
+			t.Errorf("%q: got %v", test.literal, got)
+			continue
+		}
+		token := tokens[3]
+		value := token.Value.(Value)
+		if value.Kind != test.kind || value.String() != test.want || token.EndPos-token.Pos+1 != len(test.literal) {
+			t.Errorf("%q: got %v %q spanning %d-%d, want %v %q", test.literal, value.Kind, value.String(), token.Pos, token.EndPos, test.kind, test.want)
+		}
+	}
+
+	ql := CSVQL{Sql: "SELECT 1e999"}
+	ql.Tokenizer()
+	if err, ok := ql.Error.(*SyntaxError); !ok || err.Message != "invalid number 1e999" || err.Pos != 7 || err.EndPos != 11 {
+		t.Errorf("1e999: got %v, want invalid number error at 7-11", ql.Error)
+	}
+}
+
+func TestTokenizerMinus(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []TokenType
+	}{
+		// Hyphen after operand is binary minus
+		{"5-3", []TokenType{TokenNumber, TokenMinus, TokenNumber, TokenEOF}},
+		{"a -1", []TokenType{TokenIdent, TokenMinus, TokenNumber, TokenEOF}},
+		{"(a) - 1", []TokenType{TokenLParen, TokenIdent, TokenRParen, TokenMinus, TokenNumber, TokenEOF}},
+		// Hyphen after operator or parenthesis is sign of number
+		{"(-1)", []TokenType{TokenLParen, TokenNumber, TokenRParen, TokenEOF}},
+		{"x*-2", []TokenType{TokenIdent, TokenStar, TokenNumber, TokenEOF}},
+		{"x > -2.5", []TokenType{TokenIdent, TokenGreater, TokenNumber, TokenEOF}},
+		// Dot after identifier qualifies column
+		{"t.a", []TokenType{TokenIdent, TokenDot, TokenIdent, TokenEOF}},
+	}
+	for _, test := range tests {
+		if got := tokenTypes(tokenize(t, test.sql)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.sql, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,701 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ValueKind int
+
+const (
+	KindNull ValueKind = iota
+	KindBool
+	KindInt
+	KindFloat
+	KindDecimal
+	KindDate
+	KindTimestamp
+	KindString
+)
+
+// Significant digits kept when quotient of exact decimals has no exact decimal form
+const DivisionDigits = 16
+
+// Typed value of a cell, literal or computed expression
+type Value struct {
+	Kind    ValueKind
+	Int     int64
+	Float   float64
+	Decimal *big.Rat
+	Scale   int
+	Bool    bool
+	Time    time.Time
+	Str     string
+	Text    string // original text of CSV cell, empty for literals and computed values
+}
+
+// Cell text treated as NULL. Markers match exactly, except NULL which matches in any case like SQL keyword.
+// Other markers, e.g. NA, are opt-in
+var NullMarkers = []string{"", "NULL", `\N`}
+
+// Replace NULL markers, e.g. from command line flag
+func SetNullMarkers(markers []string) {
+	NullMarkers = markers
+}
+
+// Cell of NULL in rows built by query, e.g. padding of outer join. It is NULL whatever markers are set,
+// and is printed as empty cell. Tables with NUL bytes are rejected on scan, so no input cell is taken for it
+const NullCell = "\x00"
+
+func IsNullMarker(str string) bool {
+	if str == NullCell {
+		return true
+	}
+	for _, marker := range NullMarkers {
+		if str == marker || marker == "NULL" && strings.EqualFold(str, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.\d*|\.\d+)$`)
+
+var dateLayout = "2006-01-02"
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+}
+
+func NullValue() Value {
+	return Value{Kind: KindNull}
+}
+
+func BoolValue(value bool) Value {
+	return Value{Kind: KindBool, Bool: value}
+}
+
+func IntValue(value int64) Value {
+	return Value{Kind: KindInt, Int: value}
+}
+
+func FloatValue(value float64) Value {
+	return Value{Kind: KindFloat, Float: value}
+}
+
+func DecimalValue(value *big.Rat, scale int) Value {
+	return Value{Kind: KindDecimal, Decimal: value, Scale: scale}
+}
+
+func DateValue(value time.Time) Value {
+	return Value{Kind: KindDate, Time: value}
+}
+
+func TimestampValue(value time.Time) Value {
+	return Value{Kind: KindTimestamp, Time: value}
+}
+
+func StringValue(value string) Value {
+	return Value{Kind: KindString, Str: value}
+}
+
+// Infer type of raw CSV cell or literal text.
+// Order: NULL marker, int, decimal, float, bool, date, timestamp, otherwise string
+func ParseValue(str string) Value {
+	trimmed := strings.TrimSpace(str)
+	if IsNullMarker(trimmed) {
+		return NullValue()
+	}
+	if len(trimmed) == 0 {
+		return StringValue(str)
+	}
+
+	if number, ok := ParseNumberValue(trimmed); ok {
+		return number
+	}
+
+	switch strings.ToLower(trimmed) {
+	case "true":
+		return BoolValue(true)
+	case "false":
+		return BoolValue(false)
+	}
+
+	if temporal, ok := ParseTemporalValue(trimmed); ok {
+		return temporal
+	}
+	return StringValue(str)
+}
+
+// Value of CSV cell: type is inferred, original text is kept so string functions, ||, DISTINCT and
+// GROUP BY see 00501 or 1e3 as written
+func CellValue(str string) Value {
+	value := ParseValue(str)
+	if !value.IsNull() {
+		value.Text = str
+	}
+	return value
+}
+
+// Value without original cell text, printed in canonical form of its type
+func (v Value) Typed() Value {
+	v.Text = ""
+	return v
+}
+
+// Parse numeric text into int, exact decimal or float value
+func ParseNumberValue(str string) (Value, bool) {
+	first := str[0]
+	if !IsNumber(int(first)) && first != '-' && first != '+' && first != '.' {
+		return Value{}, false
+	}
+
+	if number, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return IntValue(number), true
+	}
+
+	if decimalPattern.MatchString(str) {
+		rat, ok := new(big.Rat).SetString(str)
+		if ok {
+			scale := len(str) - strings.Index(str, ".") - 1
+			return DecimalValue(rat, scale), true
+		}
+	}
+
+	number, err := strconv.ParseFloat(str, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return Value{}, false
+	}
+	// Integers too large for int64 stay exact
+	if !strings.ContainsAny(str, ".eE") {
+		rat, ok := new(big.Rat).SetString(str)
+		if ok {
+			return DecimalValue(rat, 0), true
+		}
+	}
+	return FloatValue(number), true
+}
+
+// Parse date (2006-01-02) or timestamp text
+func ParseTemporalValue(str string) (Value, bool) {
+	if len(str) < len(dateLayout) || !IsNumber(int(str[0])) {
+		return Value{}, false
+	}
+	if len(str) == len(dateLayout) {
+		date, err := time.Parse(dateLayout, str)
+		if err != nil {
+			return Value{}, false
+		}
+		return DateValue(date), true
+	}
+	for _, layout := range timestampLayouts {
+		timestamp, err := time.Parse(layout, str)
+		if err == nil {
+			return TimestampValue(timestamp), true
+		}
+	}
+	return Value{}, false
+}
+
+// Convert Go value (literal token value, cell string, number) into Value
+func ToValue(value interface{}) Value {
+	switch typed := value.(type) {
+	case Value:
+		return typed
+	case nil:
+		return NullValue()
+	case string:
+		return ParseValue(typed)
+	case int:
+		return IntValue(int64(typed))
+	case int64:
+		return IntValue(typed)
+	case float64:
+		return FloatValue(typed)
+	case bool:
+		return BoolValue(typed)
+	default:
+		return StringValue(Stringify(value))
+	}
+}
+
+func (v Value) IsNull() bool {
+	return v.Kind == KindNull
+}
+
+func (v Value) IsNumeric() bool {
+	return v.Kind == KindInt || v.Kind == KindFloat || v.Kind == KindDecimal
+}
+
+func (v Value) IsTemporal() bool {
+	return v.Kind == KindDate || v.Kind == KindTimestamp
+}
+
+// Truthiness used by WHERE: booleans as is, numbers when non-zero
+func (v Value) IsTrue() bool {
+	switch v.Kind {
+	case KindBool:
+		return v.Bool
+	case KindInt:
+		return v.Int != 0
+	case KindFloat:
+		return v.Float != 0
+	case KindDecimal:
+		return v.Decimal.Sign() != 0
+	default:
+		return false
+	}
+}
+
+func (v Value) AsFloat() float64 {
+	switch v.Kind {
+	case KindInt:
+		return float64(v.Int)
+	case KindFloat:
+		return v.Float
+	case KindDecimal:
+		number, _ := v.Decimal.Float64()
+		return number
+	case KindBool:
+		return float64(BooleanToInt(v.Bool))
+	default:
+		return 0
+	}
+}
+
+// Exact rational form of int or decimal value
+func (v Value) AsRat() *big.Rat {
+	switch v.Kind {
+	case KindInt:
+		return new(big.Rat).SetInt64(v.Int)
+	case KindDecimal:
+		return v.Decimal
+	default:
+		rat, _ := new(big.Rat).SetString(strconv.FormatFloat(v.AsFloat(), 'g', -1, 64))
+		if rat == nil {
+			return new(big.Rat)
+		}
+		return rat
+	}
+}
+
+func (v Value) String() string {
+	if len(v.Text) > 0 && !v.IsNull() {
+		return v.Text
+	}
+	switch v.Kind {
+	case KindNull:
+		return ""
+	case KindBool:
+		return strconv.FormatBool(v.Bool)
+	case KindInt:
+		return strconv.FormatInt(v.Int, 10)
+	case KindFloat:
+		abs := math.Abs(v.Float)
+		if abs != 0 && (abs >= 1e21 || abs < 1e-6) {
+			return strconv.FormatFloat(v.Float, 'g', -1, 64)
+		}
+		return strconv.FormatFloat(v.Float, 'f', -1, 64)
+	case KindDecimal:
+		return v.Decimal.FloatString(v.Scale)
+	case KindDate:
+		return v.Time.Format(dateLayout)
+	case KindTimestamp:
+		layout := "2006-01-02 15:04:05.999999999"
+		if v.Time.Location() != time.UTC {
+			layout += "Z07:00"
+		}
+		return v.Time.Format(layout)
+	default:
+		return v.Str
+	}
+}
+
+// Text of value written into row, NULL is written as NullCell
+func (v Value) Cell() string {
+	if v.IsNull() {
+		return NullCell
+	}
+	return v.String()
+}
+
+// Round rational number to given number of digits after decimal point, half away from zero.
+// Negative scale rounds to tens, hundreds, ...
+func RoundRat(rat *big.Rat, scale int) *big.Rat {
+	factor := ScaleFactor(scale)
+	scaled := new(big.Rat).Mul(rat, factor)
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(scaled.Denom()) >= 0 {
+		if scaled.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).Quo(new(big.Rat).SetInt(quotient), factor)
+}
+
+// 10^scale as rational number, scale may be negative
+func ScaleFactor(scale int) *big.Rat {
+	exponent := big.NewInt(int64(scale))
+	factor := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), new(big.Int).Abs(exponent), nil))
+	if scale < 0 {
+		factor.Inv(factor)
+	}
+	return factor
+}
+
+// Convert whole number into int, e.g. 3.0 into 3
+func ToInteger(value Value) (Value, bool) {
+	switch value.Kind {
+	case KindInt:
+		return value, true
+	case KindDecimal:
+		if value.Decimal.IsInt() && value.Decimal.Num().IsInt64() {
+			return IntValue(value.Decimal.Num().Int64()), true
+		}
+	case KindFloat:
+		if value.Float == math.Trunc(value.Float) && math.Abs(value.Float) < math.MaxInt64 {
+			return IntValue(int64(value.Float)), true
+		}
+	}
+	return value, false
+}
+
+// Smallest scale between minScale and maxScale that represents rat exactly
+func FitScale(rat *big.Rat, minScale, maxScale int) int {
+	for scale := minScale; scale < maxScale; scale++ {
+		if RoundRat(rat, scale).Cmp(rat) == 0 {
+			return scale
+		}
+	}
+	return maxScale
+}
+
+// Kind used to compute two numeric values: float wins over decimal, decimal over int
+func NumericKind(left, right Value) ValueKind {
+	if left.Kind == KindFloat || right.Kind == KindFloat {
+		return KindFloat
+	}
+	if left.Kind == KindDecimal || right.Kind == KindDecimal {
+		return KindDecimal
+	}
+	return KindInt
+}
+
+// Coerce numeric strings for arithmetic, e.g. '5' + 1 or '00501' + 1. Number used in arithmetic loses its cell text
+func CoerceNumeric(value Value) Value {
+	if value.Kind != KindString {
+		return value.Typed()
+	}
+	if trimmed := strings.TrimSpace(value.Str); len(trimmed) > 0 {
+		if number, ok := ParseNumberValue(trimmed); ok {
+			return number
+		}
+	}
+	return value
+}
+
+// Handle compute for arithmetic operators (+ - * / %).
+// NULL operand gives NULL; int overflow falls back to exact decimal;
+// date +/- integer shifts by days, date - date gives number of days
+func ArithmeticValues(left Value, op TokenType, right Value) (Value, error) {
+	if left.IsNull() || right.IsNull() {
+		return NullValue(), nil
+	}
+	// String next to date is read as date, e.g. hire_date - '2020-01-01'
+	if left.Kind == KindString && right.IsTemporal() {
+		left = CoerceString(left, right.Kind)
+	} else if right.Kind == KindString && left.IsTemporal() {
+		right = CoerceString(right, left.Kind)
+	}
+	left = CoerceNumeric(left)
+	right = CoerceNumeric(right)
+
+	if left.IsTemporal() || right.IsTemporal() {
+		return TemporalArithmetic(left, op, right)
+	}
+	if !left.IsNumeric() || !right.IsNumeric() {
+		return NullValue(), fmt.Errorf("Operator %v can not be applied to %v and %v", op, left.Kind, right.Kind)
+	}
+
+	switch op {
+	case TokenPlus:
+		return AddValues(left, right)
+	case TokenMinus:
+		return SubtractValues(left, right)
+	case TokenStar:
+		return MultiplyValues(left, right)
+	case TokenSlash:
+		return DivideValues(left, right)
+	case TokenPercent:
+		return ModuloValues(left, right)
+	default:
+		return NullValue(), fmt.Errorf("Unsupported operator %v", op)
+	}
+}
+
+func AddValues(left, right Value) (Value, error) {
+	if left.IsNull() || right.IsNull() {
+		return NullValue(), nil
+	}
+	if !left.IsNumeric() || !right.IsNumeric() {
+		return NullValue(), fmt.Errorf("Cannot add %v and %v", left.Kind, right.Kind)
+	}
+	switch NumericKind(left, right) {
+	case KindInt:
+		sum := left.Int + right.Int
+		// Overflow => fall back to exact decimal
+		if (sum > left.Int) == (right.Int > 0) {
+			return IntValue(sum), nil
+		}
+		return DecimalValue(new(big.Rat).Add(left.AsRat(), right.AsRat()), 0), nil
+	case KindDecimal:
+		return DecimalValue(new(big.Rat).Add(left.AsRat(), right.AsRat()), max(left.Scale, right.Scale)), nil
+	default:
+		return FloatValue(left.AsFloat() + right.AsFloat()), nil
+	}
+}
+
+func SubtractValues(left, right Value) (Value, error) {
+	negated, err := NegateValue(right)
+	if err != nil {
+		return NullValue(), err
+	}
+	return AddValues(left, negated)
+}
+
+func MultiplyValues(left, right Value) (Value, error) {
+	switch NumericKind(left, right) {
+	case KindInt:
+		product := left.Int * right.Int
+		isOverflow := left.Int != 0 && (product/left.Int != right.Int || (left.Int == -1 && right.Int == math.MinInt64))
+		if !isOverflow {
+			return IntValue(product), nil
+		}
+		return DecimalValue(new(big.Rat).Mul(left.AsRat(), right.AsRat()), 0), nil
+	case KindDecimal:
+		return DecimalValue(new(big.Rat).Mul(left.AsRat(), right.AsRat()), left.Scale+right.Scale), nil
+	default:
+		return FloatValue(left.AsFloat() * right.AsFloat()), nil
+	}
+}
+
+// Exact operands give a decimal, float operands a float. Decimal quotient is exact when it fits in
+// DivisionDigits significant digits, otherwise it is rounded to them, e.g. 1 / 30000 = 0.00003333333333333333
+// and 2 / 3 * 3 = 2.0000000000000001. Scale is never less than scale of operands
+func DivideValues(left, right Value) (Value, error) {
+	if left.IsNull() || right.IsNull() {
+		return NullValue(), nil
+	}
+	if !left.IsNumeric() || !right.IsNumeric() {
+		return NullValue(), fmt.Errorf("Cannot divide %v by %v", left.Kind, right.Kind)
+	}
+	if NumericKind(left, right) == KindFloat {
+		if right.AsFloat() == 0 {
+			return NullValue(), fmt.Errorf("Division by zero")
+		}
+		return FloatValue(left.AsFloat() / right.AsFloat()), nil
+	}
+	if right.AsRat().Sign() == 0 {
+		return NullValue(), fmt.Errorf("Division by zero")
+	}
+	quotient := new(big.Rat).Quo(left.AsRat(), right.AsRat())
+	minScale := max(left.Scale, right.Scale)
+	scale := FitScale(quotient, minScale, max(minScale, DivisionDigits-IntegerDigits(quotient)))
+	return DecimalValue(RoundRat(quotient, scale), scale), nil
+}
+
+// Number of digits before decimal point, zero or negative for zeros after it, e.g. 3 for 123.4 and -4 for 0.00003
+func IntegerDigits(rat *big.Rat) int {
+	abs := new(big.Rat).Abs(rat)
+	if abs.Sign() == 0 {
+		return 0
+	}
+	if whole := new(big.Int).Quo(abs.Num(), abs.Denom()); whole.Sign() > 0 {
+		return len(whole.String())
+	}
+	digits := 0
+	for abs.Cmp(big.NewRat(1, 10)) < 0 {
+		abs.Mul(abs, big.NewRat(10, 1))
+		digits--
+	}
+	return digits
+}
+
+// Remainder keeps sign of dividend, like Go and SQL
+func ModuloValues(left, right Value) (Value, error) {
+	switch NumericKind(left, right) {
+	case KindInt:
+		if right.Int == 0 {
+			return NullValue(), fmt.Errorf("Division by zero")
+		}
+		if right.Int == -1 {
+			return IntValue(0), nil
+		}
+		return IntValue(left.Int % right.Int), nil
+	case KindDecimal:
+		if right.AsRat().Sign() == 0 {
+			return NullValue(), fmt.Errorf("Division by zero")
+		}
+		quotient := new(big.Rat).Quo(left.AsRat(), right.AsRat())
+		truncated := new(big.Int).Quo(quotient.Num(), quotient.Denom())
+		remainder := new(big.Rat).Sub(left.AsRat(), new(big.Rat).Mul(right.AsRat(), new(big.Rat).SetInt(truncated)))
+		return DecimalValue(remainder, max(left.Scale, right.Scale)), nil
+	default:
+		if right.AsFloat() == 0 {
+			return NullValue(), fmt.Errorf("Division by zero")
+		}
+		return FloatValue(math.Mod(left.AsFloat(), right.AsFloat())), nil
+	}
+}
+
+// Unary minus
+func NegateValue(value Value) (Value, error) {
+	value = CoerceNumeric(value)
+	switch value.Kind {
+	case KindNull:
+		return NullValue(), nil
+	case KindInt:
+		if value.Int == math.MinInt64 {
+			return DecimalValue(new(big.Rat).Neg(value.AsRat()), 0), nil
+		}
+		return IntValue(-value.Int), nil
+	case KindDecimal:
+		return DecimalValue(new(big.Rat).Neg(value.Decimal), value.Scale), nil
+	case KindFloat:
+		return FloatValue(-value.Float), nil
+	default:
+		return NullValue(), fmt.Errorf("Operator '-' can not be applied to %v", value.Kind)
+	}
+}
+
+// Date and timestamp arithmetic with whole days
+func TemporalArithmetic(left Value, op TokenType, right Value) (Value, error) {
+	switch {
+	case left.IsTemporal() && right.Kind == KindInt && (op == TokenPlus || op == TokenMinus):
+		days := right.Int
+		if op == TokenMinus {
+			days = -days
+		}
+		return Value{Kind: left.Kind, Time: left.Time.AddDate(0, 0, int(days))}, nil
+	case left.Kind == KindInt && right.IsTemporal() && op == TokenPlus:
+		return Value{Kind: right.Kind, Time: right.Time.AddDate(0, 0, int(left.Int))}, nil
+	case left.Kind == KindDate && right.Kind == KindDate && op == TokenMinus:
+		return IntValue(int64(math.Round(left.Time.Sub(right.Time).Hours() / 24))), nil
+	case left.IsTemporal() && right.IsTemporal() && op == TokenMinus:
+		return FloatValue(left.Time.Sub(right.Time).Hours() / 24), nil
+	default:
+		return NullValue(), fmt.Errorf("Operator %v can not be applied to %v and %v", op, left.Kind, right.Kind)
+	}
+}
+
+// String concatenation, NULL operand gives NULL
+func ConcatValues(left, right Value) Value {
+	if left.IsNull() || right.IsNull() {
+		return NullValue()
+	}
+	return StringValue(left.String() + right.String())
+}
+
+func (kind ValueKind) String() string {
+	switch kind {
+	case KindNull:
+		return "NULL"
+	case KindBool:
+		return "boolean"
+	case KindInt:
+		return "integer"
+	case KindFloat:
+		return "float"
+	case KindDecimal:
+		return "decimal"
+	case KindDate:
+		return "date"
+	case KindTimestamp:
+		return "timestamp"
+	default:
+		return "string"
+	}
+}
+
+func CompareInt(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// Compare two values, return -1, 0, 1 and whether they are comparable.
+// Coercion rules:
+//   - NULL is not comparable with anything
+//   - numbers compare numerically across int, decimal and float
+//   - dates and timestamps compare chronologically, a date is midnight of that day
+//   - a number read from CSV cell compares with a string by text, so zip = '00501' doesn't match 501
+//   - a string compared with a number, date or boolean is parsed as that type first
+//   - everything else compares by text
+func CompareValues(left, right Value) (int, bool) {
+	if left.IsNull() || right.IsNull() {
+		return 0, false
+	}
+	if IsCellNumber(left) && right.Kind == KindString || left.Kind == KindString && IsCellNumber(right) {
+		return strings.Compare(left.String(), right.String()), true
+	}
+
+	if left.Kind == KindString && right.Kind != KindString {
+		left = CoerceString(left, right.Kind)
+	} else if right.Kind == KindString && left.Kind != KindString {
+		right = CoerceString(right, left.Kind)
+	}
+
+	switch {
+	case left.IsNumeric() && right.IsNumeric():
+		switch NumericKind(left, right) {
+		case KindInt:
+			return CompareInt(left.Int, right.Int), true
+		case KindDecimal:
+			return left.AsRat().Cmp(right.AsRat()), true
+		default:
+			return CompareNumber(left.AsFloat(), right.AsFloat()), true
+		}
+	case left.IsTemporal() && right.IsTemporal():
+		return left.Time.Compare(right.Time), true
+	case left.Kind == KindBool && right.Kind == KindBool:
+		return BooleanToInt(left.Bool) - BooleanToInt(right.Bool), true
+	default:
+		return strings.Compare(left.String(), right.String()), true
+	}
+}
+
+// Number with original text of CSV cell
+func IsCellNumber(value Value) bool {
+	return value.IsNumeric() && len(value.Text) > 0
+}
+
+// Parse string value as target kind, keep string when it doesn't fit.
+// Number is only read from text written the way it prints, so '00501' and '1e3' stay strings
+func CoerceString(value Value, kind ValueKind) Value {
+	parsed := ParseValue(value.Str)
+	if parsed.IsNumeric() && parsed.String() != strings.TrimSpace(value.Str) {
+		return value
+	}
+	isSameFamily := (parsed.IsNumeric() && (kind == KindInt || kind == KindFloat || kind == KindDecimal)) ||
+		(parsed.IsTemporal() && (kind == KindDate || kind == KindTimestamp)) ||
+		(parsed.Kind == KindBool && kind == KindBool)
+	if isSameFamily {
+		return parsed
+	}
+	return value
+}
@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		text string
+		kind ValueKind
+		want string
+	}{
+		{"", KindNull, ""},
+		{"NULL", KindNull, ""},
+		{"42", KindInt, "42"},
+		{"-7", KindInt, "-7"},
+		{"00501", KindInt, "501"},
+		{"9.99", KindDecimal, "9.99"},
+		{"1e3", KindFloat, "1000"},
+		{"99999999999999999999", KindDecimal, "99999999999999999999"},
+		{"true", KindBool, "true"},
+		{"2024-02-29", KindDate, "2024-02-29"},
+		{"2024-02-29 10:30:00", KindTimestamp, "2024-02-29 10:30:00"},
+		{"apple", KindString, "apple"},
+	}
+	for _, test := range tests {
+		value := ParseValue(test.text)
+		if value.Kind != test.kind || value.String() != test.want {
+			t.Errorf("ParseValue(%q) = %v %q, want %v %q", test.text, value.Kind, value.String(), test.kind, test.want)
+		}
+	}
+}
+
+func TestCellValueKeepsText(t *testing.T) {
+	for _, text := range []string{"00501", "1e3", "1.50", "TRUE"} {
+		value := CellValue(text)
+		if value.String() != text {
+			t.Errorf("CellValue(%q).String() = %q", text, value.String())
+		}
+	}
+	if value := CellValue("00501").Typed(); value.String() != "501" {
+		t.Errorf("CellValue(00501).Typed() = %q, want 501", value.String())
+	}
+	if value := CellValue("NULL"); !value.IsNull() {
+		t.Errorf("CellValue(NULL) = %v, want NULL", value.Kind)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name         string
+		left, right  Value
+		want         int
+		isComparable bool
+	}{
+		{"strings", StringValue("apple"), StringValue("banana"), -1, true},
+		{"decimal and int", ParseValue("1.5"), ParseValue("2"), -1, true},
+		{"int and decimal", ParseValue("1"), ParseValue("1.0"), 0, true},
+		{"float and int", ParseValue("1e3"), ParseValue("1000"), 0, true},
+		{"dates", ParseValue("2024-01-02"), ParseValue("2023-12-31"), 1, true},
+		{"date and timestamp", ParseValue("2024-01-02"), ParseValue("2024-01-02 00:00:00"), 0, true},
+		{"number literal and numeric string", IntValue(5), StringValue("5"), 0, true},
+		{"cells by number", CellValue("00501"), CellValue("501"), 0, true},
+		{"cell and string by text", CellValue("00501"), StringValue("501"), -1, true},
+		{"cell and same string", CellValue("00501"), StringValue("00501"), 0, true},
+		{"exponent cell and string", CellValue("1e3"), StringValue("1000"), 1, true},
+		{"leading zero string stays text", StringValue("00501"), IntValue(501), -1, true},
+		{"NULL", NullValue(), IntValue(1), 0, false},
+	}
+	for _, test := range tests {
+		got, isComparable := CompareValues(test.left, test.right)
+		if got != test.want || isComparable != test.isComparable {
+			t.Errorf("%v: CompareValues = %d, %v, want %d, %v", test.name, got, isComparable, test.want, test.isComparable)
+		}
+	}
+}
+
+func TestDivideValues(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		want        string
+	}{
+		{IntValue(10), IntValue(4), "2.5"},
+		{IntValue(10), IntValue(2), "5"},
+		{ParseValue("1.50"), IntValue(3), "0.50"},
+		{IntValue(2), IntValue(3), "0.6666666666666667"},
+		{IntValue(1), IntValue(30000), "0.00003333333333333333"},
+		{IntValue(-145000), IntValue(3), "-48333.33333333333"},
+		{ParseValue("1e3"), IntValue(8), "125"},
+	}
+	for _, test := range tests {
+		got, err := DivideValues(test.left, test.right)
+		if err != nil || got.String() != test.want {
+			t.Errorf("DivideValues(%v, %v) = %q, %v, want %q", test.left.String(), test.right.String(), got.String(), err, test.want)
+		}
+	}
+
+	// Rounded quotient stays rounded in later arithmetic
+	quotient, _ := DivideValues(IntValue(2), IntValue(3))
+	if product, _ := MultiplyValues(quotient, IntValue(3)); product.String() != "2.0000000000000001" {
+		t.Errorf("2 / 3 * 3 = %q, want 2.0000000000000001", product.String())
+	}
+	if _, err := DivideValues(IntValue(1), IntValue(0)); err == nil {
+		t.Error("DivideValues(1, 0) want division by zero error")
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		isSame      bool
+	}{
+		{CellValue("00501"), CellValue("501"), false},
+		{CellValue("1e3"), CellValue("1000"), false},
+		{CellValue("1000"), IntValue(1000), true},
+		{NullValue(), NullValue(), true},
+		{NullValue(), StringValue(""), false},
+	}
+	for _, test := range tests {
+		isSame := GroupKey([]Value{test.left}) == GroupKey([]Value{test.right})
+		if isSame != test.isSame {
+			t.Errorf("GroupKey(%q) == GroupKey(%q) is %v, want %v", test.left.String(), test.right.String(), isSame, test.isSame)
+		}
+	}
+	// Key parts are length-prefixed, so splitting text between values doesn't collide
+	if GroupKey([]Value{StringValue("a_b"), StringValue("c")}) == GroupKey([]Value{StringValue("a"), StringValue("b_c")}) {
+		t.Error("GroupKey collides for a_b, c and a, b_c")
+	}
+}
+
+// Replace NULL markers for the duration of test
+func setNullMarkers(t *testing.T, markers ...string) {
+	t.Helper()
+	defaultMarkers := NullMarkers
+	SetNullMarkers(markers)
+	t.Cleanup(func() { SetNullMarkers(defaultMarkers) })
+}
+
+func TestIsNullMarker(t *testing.T) {
+	for text, want := range map[string]bool{"": true, "NULL": true, "null": true, `\N`: true, `\n`: false, "NA": false, "na": false} {
+		if got := IsNullMarker(text); got != want {
+			t.Errorf("IsNullMarker(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	setNullMarkers(t, "NULL", "NA")
+	for text, want := range map[string]bool{"": false, "Null": true, "NA": true, "na": false, "Na": false} {
+		if got := IsNullMarker(text); got != want {
+			t.Errorf("with markers NULL, NA: IsNullMarker(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestSetNullMarkers(t *testing.T) {
+	setNullMarkers(t, "NA", "-")
+	if !reflect.DeepEqual(NullMarkers, []string{"NA", "-"}) {
+		t.Fatalf("NullMarkers = %q, want [NA -]", NullMarkers)
+	}
+	// Markers replace defaults, so empty cell is text and NULL only matches when given
+	for text, want := range map[string]bool{"NA": true, "-": true, "": false, "NULL": false, `\N`: false, NullCell: true} {
+		if got := CellValue(text).IsNull(); got != want {
+			t.Errorf("with markers NA, -: CellValue(%q).IsNull() = %v, want %v", text, got, want)
+		}
+	}
+
+	// Cell written by query stays NULL without any marker
+	setNullMarkers(t)
+	if !IsNullMarker(NullCell) || IsNullMarker("") {
+		t.Errorf("without markers: IsNullMarker(NullCell) = %v, IsNullMarker(\"\") = %v, want true, false", IsNullMarker(NullCell), IsNullMarker(""))
+	}
+}
@@ -0,0 +1,703 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Struct of window function call, e.g. RANK() OVER (PARTITION BY dept ORDER BY salary DESC)
+type WindowExpr struct {
+	Name        Token
+	Args        []Expr
+	Aggregate   *AggregateExpr // Set when aggregate function is used as window function, e.g. SUM(salary) OVER (...)
+	PartitionBy []Expr
+	OrderBy     []OrderBySingle
+	Frame       *WindowFrame // nil for default frame
+}
+
+// Rows around current row that window aggregate, FIRST_VALUE and LAST_VALUE are computed over
+type WindowFrame struct {
+	Unit  TokenType // TokenRows or TokenRange
+	Start FrameBound
+	End   FrameBound
+}
+
+type FrameBound struct {
+	Type   TokenType // TokenUnboundedPreceding, TokenPreceding, TokenCurrentRow, TokenFollowing or TokenUnboundedFollowing
+	Offset Value     // N of "N PRECEDING" and "N FOLLOWING"
+}
+
+// Order of frame bound types, frame can not start after it ends
+var frameBoundOrder = []TokenType{TokenUnboundedPreceding, TokenPreceding, TokenCurrentRow, TokenFollowing, TokenUnboundedFollowing}
+
+// Definition of function that is only usable as window function
+type WindowFunction struct {
+	Name    string
+	MinArgs int
+	MaxArgs int
+}
+
+var windowFunctions = map[string]WindowFunction{}
+
+// Add window function to registry, lookup is case-insensitive
+func RegisterWindowFunction(function WindowFunction) {
+	windowFunctions[function.Name] = function
+}
+
+func init() {
+	RegisterWindowFunction(WindowFunction{Name: "ROW_NUMBER", MinArgs: 0, MaxArgs: 0})
+	RegisterWindowFunction(WindowFunction{Name: "RANK", MinArgs: 0, MaxArgs: 0})
+	RegisterWindowFunction(WindowFunction{Name: "DENSE_RANK", MinArgs: 0, MaxArgs: 0})
+	RegisterWindowFunction(WindowFunction{Name: "NTILE", MinArgs: 1, MaxArgs: 1})
+	RegisterWindowFunction(WindowFunction{Name: "LAG", MinArgs: 1, MaxArgs: 3})
+	RegisterWindowFunction(WindowFunction{Name: "LEAD", MinArgs: 1, MaxArgs: 3})
+	RegisterWindowFunction(WindowFunction{Name: "FIRST_VALUE", MinArgs: 1, MaxArgs: 1})
+	RegisterWindowFunction(WindowFunction{Name: "LAST_VALUE", MinArgs: 1, MaxArgs: 1})
+}
+
+func GetWindowFunction(name string) (WindowFunction, bool) {
+	function, ok := windowFunctions[strings.ToUpper(name)]
+	return function, ok
+}
+
+// Handle parse call of window-only function, e.g. ROW_NUMBER() OVER (...)
+func (p *Parser) ParseWindowFunctionCall(name Token) (Expr, error) {
+	function, _ := GetWindowFunction(Stringify(name.Value))
+	name.Value = function.Name
+	args, err := p.ParseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < function.MinArgs || len(args) > function.MaxArgs {
+		return nil, NewSyntaxError(name, fmt.Sprintf("function %v expects %v, got %d", function.Name, FormatArity(function.MinArgs, function.MaxArgs), len(args)))
+	}
+	window := WindowExpr{Name: name, Args: args}
+	if err := ValidateWindowArgs(window); err != nil {
+		return nil, NewSyntaxError(name, err.Error())
+	}
+	if p.current.Type != TokenOver {
+		return nil, NewSyntaxError(name, fmt.Sprintf("window function %v requires OVER clause", function.Name))
+	}
+	return p.ParseWindow(window)
+}
+
+// Check constant arguments: bucket count of NTILE, offset of LAG and LEAD
+func ValidateWindowArgs(window WindowExpr) error {
+	switch window.Name.Value {
+	case "NTILE":
+		{
+			buckets, err := WindowConstantInt(window, 0)
+			if err == nil && buckets <= 0 {
+				err = fmt.Errorf("function NTILE expects positive number of buckets")
+			}
+			return err
+		}
+	case "LAG", "LEAD":
+		{
+			if len(window.Args) < 2 {
+				return nil
+			}
+			offset, err := WindowConstantInt(window, 1)
+			if err == nil && offset < 0 {
+				err = fmt.Errorf("function %v expects non-negative offset", window.Name.Value)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Get constant integer argument of window function
+func WindowConstantInt(window WindowExpr, idx int) (int, error) {
+	arg := window.Args[idx]
+	if len(ExprColumns(arg)) > 0 || ContainsAggregate(arg) {
+		return 0, fmt.Errorf("argument %d of %v must be a constant", idx+1, window.Name.Value)
+	}
+	value, err := Eval(arg, nil, map[string]int{})
+	if err != nil {
+		return 0, err
+	}
+	integer, isInteger := ToInteger(value)
+	if !isInteger {
+		return 0, fmt.Errorf("argument %d of %v must be an integer, got %v", idx+1, window.Name.Value, value.String())
+	}
+	return int(integer.Int), nil
+}
+
+// Handle parse OVER clause: OVER ([PARTITION BY expr, ...] [ORDER BY expr [ASC | DESC], ...] [frame])
+func (p *Parser) ParseWindow(window WindowExpr) (Expr, error) {
+	if _, err := p.Expect(TokenOver); err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	if p.current.Type == TokenPartitionBy {
+		p.Advance()
+		for {
+			expr, err := p.ParseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			window.PartitionBy = append(window.PartitionBy, expr)
+			if p.current.Type != TokenComma {
+				break
+			}
+			p.Advance()
+		}
+	}
+	if p.current.Type == TokenOrderBy {
+		p.Advance()
+		orderBy, err := p.ParseOrderByList()
+		if err != nil {
+			return nil, err
+		}
+		window.OrderBy = orderBy
+	}
+	if p.current.Type == TokenRows || p.current.Type == TokenRange {
+		unit := p.current
+		frame, err := p.ParseWindowFrame()
+		if err != nil {
+			return nil, err
+		}
+		if frame.Unit == TokenRange && (frame.Start.Offset.Kind != KindNull || frame.End.Offset.Kind != KindNull) && len(window.OrderBy) != 1 {
+			return nil, NewSyntaxError(unit, "RANGE with offset PRECEDING or FOLLOWING requires exactly one ORDER BY column")
+		}
+		window.Frame = &frame
+	}
+	if _, err := p.Expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	for _, child := range ExprChildren(window) {
+		if ContainsWindow(child) {
+			return nil, NewSyntaxError(window.Name, "window function calls can not be nested")
+		}
+	}
+	return window, nil
+}
+
+// Handle parse frame clause: ROWS | RANGE (bound | BETWEEN bound AND bound), single bound ends at current row
+func (p *Parser) ParseWindowFrame() (WindowFrame, error) {
+	frame := WindowFrame{
+		Unit: p.current.Type,
+		End:  FrameBound{Type: TokenCurrentRow, Offset: NullValue()},
+	}
+	p.Advance()
+	startToken := p.current
+	if p.current.Type == TokenBetween {
+		p.Advance()
+		startToken = p.current
+		start, err := p.ParseFrameBound(frame.Unit)
+		if err != nil {
+			return frame, err
+		}
+		if _, err := p.Expect(TokenAnd); err != nil {
+			return frame, err
+		}
+		frame.Start = start
+	} else {
+		start, err := p.ParseFrameBound(frame.Unit)
+		if err != nil {
+			return frame, err
+		}
+		frame.Start = start
+		if start.Type == TokenFollowing || start.Type == TokenUnboundedFollowing {
+			return frame, NewSyntaxError(startToken, "frame starting after current row must use BETWEEN")
+		}
+		return frame, nil
+	}
+	endToken := p.current
+	end, err := p.ParseFrameBound(frame.Unit)
+	if err != nil {
+		return frame, err
+	}
+	frame.End = end
+	if frame.Start.Type == TokenUnboundedFollowing {
+		return frame, NewSyntaxError(startToken, "frame start can not be UNBOUNDED FOLLOWING")
+	}
+	if frame.End.Type == TokenUnboundedPreceding {
+		return frame, NewSyntaxError(endToken, "frame end can not be UNBOUNDED PRECEDING")
+	}
+	if slices.Index(frameBoundOrder, frame.Start.Type) > slices.Index(frameBoundOrder, frame.End.Type) {
+		return frame, NewSyntaxError(endToken, "frame can not end before it starts")
+	}
+	return frame, nil
+}
+
+// Handle parse frame bound: UNBOUNDED PRECEDING | N PRECEDING | CURRENT ROW | N FOLLOWING | UNBOUNDED FOLLOWING
+func (p *Parser) ParseFrameBound(unit TokenType) (FrameBound, error) {
+	token := p.current
+	switch token.Type {
+	case TokenUnboundedPreceding, TokenCurrentRow, TokenUnboundedFollowing:
+		{
+			p.Advance()
+			return FrameBound{Type: token.Type, Offset: NullValue()}, nil
+		}
+	case TokenNumber:
+		{
+			offset := ParseValue(Stringify(token.Value))
+			if _, isInteger := ToInteger(offset); unit == TokenRows && !isInteger {
+				return FrameBound{}, NewSyntaxError(token, "ROWS frame offset must be an integer")
+			}
+			p.Advance()
+			if p.current.Type != TokenPreceding && p.current.Type != TokenFollowing {
+				return FrameBound{}, UnexpectedTokenError(p.current, TokenPreceding, TokenFollowing)
+			}
+			bound := FrameBound{Type: p.current.Type, Offset: offset}
+			p.Advance()
+			return bound, nil
+		}
+	default:
+		{
+			return FrameBound{}, UnexpectedTokenError(token, TokenUnboundedPreceding, TokenNumber, TokenCurrentRow, TokenUnboundedFollowing)
+		}
+	}
+}
+
+// SQL text of window function call
+func WindowString(window WindowExpr) string {
+	call := ""
+	if window.Aggregate != nil {
+		call = AggregateString(*window.Aggregate)
+	} else {
+		args := []string{}
+		for _, arg := range window.Args {
+			args = append(args, ExprString(arg))
+		}
+		call = fmt.Sprintf("%v(%v)", window.Name.Value, strings.Join(args, ", "))
+	}
+	parts := []string{}
+	if len(window.PartitionBy) > 0 {
+		partitionBy := []string{}
+		for _, expr := range window.PartitionBy {
+			partitionBy = append(partitionBy, ExprString(expr))
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(partitionBy, ", "))
+	}
+	if len(window.OrderBy) > 0 {
+		parts = append(parts, OrderByString(window.OrderBy))
+	}
+	if window.Frame != nil {
+		parts = append(parts, fmt.Sprintf("%v BETWEEN %v AND %v", window.Frame.Unit, FrameBoundString(window.Frame.Start), FrameBoundString(window.Frame.End)))
+	}
+	return fmt.Sprintf("%v OVER (%v)", call, strings.Join(parts, " "))
+}
+
+func FrameBoundString(bound FrameBound) string {
+	if bound.Offset.IsNull() {
+		return bound.Type.String()
+	}
+	return fmt.Sprintf("%v %v", bound.Offset.String(), bound.Type)
+}
+
+// Name of window function result within source row
+func WindowKey(window WindowExpr) string {
+	return "@" + ExprString(window)
+}
+
+// Whether expression contains window function call
+func ContainsWindow(expr interface{}) bool {
+	return len(CollectWindows(expr)) > 0
+}
+
+// Window function calls within expression
+func CollectWindows(expr interface{}) []WindowExpr {
+	if window, isWindow := expr.(WindowExpr); isWindow {
+		return []WindowExpr{window}
+	}
+	windows := []WindowExpr{}
+	for _, child := range ExprChildren(expr) {
+		if child != nil {
+			windows = append(windows, CollectWindows(child)...)
+		}
+	}
+	return windows
+}
+
+// Distinct window function calls of select columns and ORDER BY
+func WindowSources(ast AST) []WindowExpr {
+	exprs := []interface{}{}
+	for _, col := range ast.Columns {
+		exprs = append(exprs, col.Expr)
+	}
+	for _, condition := range ast.OrderBy {
+		exprs = append(exprs, condition.Expr)
+	}
+	windows := []WindowExpr{}
+	seen := map[string]bool{}
+	for _, expr := range exprs {
+		for _, window := range CollectWindows(expr) {
+			key := WindowKey(window)
+			if !seen[key] {
+				seen[key] = true
+				windows = append(windows, window)
+			}
+		}
+	}
+	return windows
+}
+
+// Select columns that do not contain window function calls
+func NonWindowColumns(columns []Column) []Column {
+	nonWindowColumns := []Column{}
+	for _, col := range columns {
+		if !ContainsWindow(col.Expr) {
+			nonWindowColumns = append(nonWindowColumns, col)
+		}
+	}
+	return nonWindowColumns
+}
+
+// Get result of window function call from source row
+func ComputeWindow(window WindowExpr, row []string, headerIndex map[string]int) (Value, error) {
+	fieldIdx, ok := headerIndex[WindowKey(window)]
+	if !ok || fieldIdx >= len(row) {
+		return NullValue(), fmt.Errorf("Window function %v is not allowed here", ExprString(window))
+	}
+	return CellValue(row[fieldIdx]), nil
+}
+
+// Compute window functions over all source rows, after WHERE, GROUP BY and HAVING.
+// Results are appended to each row and named by WindowKey in returned header index
+func ComputeWindows(windows []WindowExpr, rows [][]string, headerIndex map[string]int) (map[string]int, error) {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	for _, idx := range headerIndex {
+		width = max(width, idx+1)
+	}
+	windowIndex := map[string]int{}
+	for name, idx := range headerIndex {
+		windowIndex[name] = idx
+	}
+	for i := range rows {
+		rows[i] = append(rows[i], make([]string, width-len(rows[i]))...)
+	}
+
+	for i, window := range windows {
+		results, err := EvalWindow(window, rows, headerIndex)
+		if err != nil {
+			return nil, err
+		}
+		for j := range rows {
+			rows[j] = append(rows[j], results[j].Cell())
+		}
+		windowIndex[WindowKey(window)] = width + i
+	}
+	return windowIndex, nil
+}
+
+// Rows of one partition sorted by window ORDER BY. Peers are rows with equal ORDER BY keys
+type WindowPartition struct {
+	Rows      []int     // Indices of source rows in window order
+	Keys      [][]Value // ORDER BY keys aligned with Rows
+	PeerStart []int     // Position of first peer of each row
+	PeerEnd   []int     // Position after last peer of each row
+}
+
+// Split rows into partitions in first-seen order, then sort each partition by window ORDER BY
+func WindowPartitions(window WindowExpr, rows [][]string, headerIndex map[string]int) ([]*WindowPartition, error) {
+	partitions := []*WindowPartition{}
+	partitionByKey := map[string]*WindowPartition{}
+	for i, row := range rows {
+		values := []Value{}
+		for _, expr := range window.PartitionBy {
+			value, err := Eval(expr, row, headerIndex)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		key := GroupKey(values)
+		partition, ok := partitionByKey[key]
+		if !ok {
+			partition = &WindowPartition{}
+			partitionByKey[key] = partition
+			partitions = append(partitions, partition)
+		}
+		keys := []Value{}
+		for _, condition := range window.OrderBy {
+			value, err := Eval(condition.Expr, row, headerIndex)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, value)
+		}
+		partition.Rows = append(partition.Rows, i)
+		partition.Keys = append(partition.Keys, keys)
+	}
+
+	for _, partition := range partitions {
+		positions := make([]int, len(partition.Rows))
+		for i := range positions {
+			positions[i] = i
+		}
+		compare := func(pos1, pos2 int) int {
+			return OrderByKeysComparator(window.OrderBy, SortableRow{Keys: partition.Keys[pos1]}, SortableRow{Keys: partition.Keys[pos2]})
+		}
+		slices.SortStableFunc(positions, compare)
+		sortedRows, sortedKeys := []int{}, [][]Value{}
+		for _, pos := range positions {
+			sortedRows = append(sortedRows, partition.Rows[pos])
+			sortedKeys = append(sortedKeys, partition.Keys[pos])
+		}
+		partition.Rows, partition.Keys = sortedRows, sortedKeys
+
+		count := len(partition.Rows)
+		partition.PeerStart = make([]int, count)
+		partition.PeerEnd = make([]int, count)
+		for pos := 0; pos < count; pos++ {
+			if pos > 0 && OrderByKeysComparator(window.OrderBy, SortableRow{Keys: partition.Keys[pos-1]}, SortableRow{Keys: partition.Keys[pos]}) == 0 {
+				partition.PeerStart[pos] = partition.PeerStart[pos-1]
+			} else {
+				partition.PeerStart[pos] = pos
+			}
+		}
+		for pos := count - 1; pos >= 0; pos-- {
+			if pos < count-1 && partition.PeerStart[pos+1] == partition.PeerStart[pos] {
+				partition.PeerEnd[pos] = partition.PeerEnd[pos+1]
+			} else {
+				partition.PeerEnd[pos] = pos + 1
+			}
+		}
+	}
+	return partitions, nil
+}
+
+// Compute window function for every source row
+func EvalWindow(window WindowExpr, rows [][]string, headerIndex map[string]int) ([]Value, error) {
+	partitions, err := WindowPartitions(window, rows, headerIndex)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Value, len(rows))
+	for _, partition := range partitions {
+		if window.Aggregate != nil {
+			err = partition.ComputeAggregate(window, rows, headerIndex, results)
+		} else {
+			err = partition.ComputeFunction(window, rows, headerIndex, results)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// Compute ranking, offset and value window functions of partition
+func (partition *WindowPartition) ComputeFunction(window WindowExpr, rows [][]string, headerIndex map[string]int, results []Value) error {
+	count := len(partition.Rows)
+	buckets, offset := 1, 1
+	switch {
+	case window.Name.Value == "NTILE":
+		buckets, _ = WindowConstantInt(window, 0)
+	case len(window.Args) > 1:
+		offset, _ = WindowConstantInt(window, 1)
+	}
+	if window.Name.Value == "LAG" {
+		offset = -offset
+	}
+	denseRank := 0
+	for pos, rowIdx := range partition.Rows {
+		if partition.PeerStart[pos] == pos {
+			denseRank++
+		}
+		switch window.Name.Value {
+		case "ROW_NUMBER":
+			{
+				results[rowIdx] = IntValue(int64(pos + 1))
+			}
+		case "RANK":
+			{
+				results[rowIdx] = IntValue(int64(partition.PeerStart[pos] + 1))
+			}
+		case "DENSE_RANK":
+			{
+				results[rowIdx] = IntValue(int64(denseRank))
+			}
+		case "NTILE":
+			{
+				// First count % buckets buckets get one row more than the others
+				size, remainder := count/buckets, count%buckets
+				bucket := 0
+				if pos < remainder*(size+1) {
+					bucket = pos / (size + 1)
+				} else {
+					bucket = remainder + (pos-remainder*(size+1))/size
+				}
+				results[rowIdx] = IntValue(int64(bucket + 1))
+			}
+		case "LAG", "LEAD":
+			{
+				value, err := partition.ValueAt(window, pos+offset, rows, headerIndex)
+				if err != nil {
+					return err
+				}
+				if pos+offset < 0 || pos+offset >= count {
+					if len(window.Args) > 2 {
+						value, err = Eval(window.Args[2], rows[rowIdx], headerIndex)
+						if err != nil {
+							return err
+						}
+					}
+				}
+				results[rowIdx] = value
+			}
+		case "FIRST_VALUE", "LAST_VALUE":
+			{
+				start, end := partition.FrameBounds(window, pos)
+				target := start
+				if window.Name.Value == "LAST_VALUE" {
+					target = end - 1
+				}
+				if start >= end {
+					target = -1
+				}
+				value, err := partition.ValueAt(window, target, rows, headerIndex)
+				if err != nil {
+					return err
+				}
+				results[rowIdx] = value
+			}
+		}
+	}
+	return nil
+}
+
+// Get first argument of window function at position of partition, NULL outside of partition
+func (partition *WindowPartition) ValueAt(window WindowExpr, pos int, rows [][]string, headerIndex map[string]int) (Value, error) {
+	if pos < 0 || pos >= len(partition.Rows) {
+		return NullValue(), nil
+	}
+	return Eval(window.Args[0], rows[partition.Rows[pos]], headerIndex)
+}
+
+// Compute aggregate over frame of every row of partition. Frames starting at partition start
+// only grow, so running aggregates add each row once instead of recomputing every frame
+func (partition *WindowPartition) ComputeAggregate(window WindowExpr, rows [][]string, headerIndex map[string]int, results []Value) error {
+	agg := *window.Aggregate
+	frameStart := TokenUnboundedPreceding
+	if window.Frame != nil {
+		frameStart = window.Frame.Start.Type
+	}
+	isRunning := frameStart == TokenUnboundedPreceding && len(agg.OrderBy) == 0
+
+	var accumulator Accumulator
+	added := 0
+	for pos, rowIdx := range partition.Rows {
+		start, end := partition.FrameBounds(window, pos)
+		if !isRunning || accumulator == nil {
+			var err error
+			accumulator, err = NewAccumulator(agg)
+			if err != nil {
+				return err
+			}
+			added = start
+		}
+		for ; added < end; added++ {
+			if err := AccumulateRow(accumulator, agg, rows[partition.Rows[added]], headerIndex); err != nil {
+				return err
+			}
+		}
+		results[rowIdx] = accumulator.Result()
+	}
+	return nil
+}
+
+// Frame of row at position as [start, end) positions of partition. Without frame clause the frame
+// is whole partition, or rows up to last peer of current row when window has ORDER BY
+func (partition *WindowPartition) FrameBounds(window WindowExpr, pos int) (int, int) {
+	count := len(partition.Rows)
+	frame := WindowFrame{
+		Unit:  TokenRange,
+		Start: FrameBound{Type: TokenUnboundedPreceding},
+		End:   FrameBound{Type: TokenUnboundedFollowing},
+	}
+	if window.Frame != nil {
+		frame = *window.Frame
+	} else if len(window.OrderBy) > 0 {
+		frame.End = FrameBound{Type: TokenCurrentRow}
+	}
+	start := partition.FrameBound(window, frame.Unit, frame.Start, pos, true)
+	end := partition.FrameBound(window, frame.Unit, frame.End, pos, false)
+	start = min(max(start, 0), count)
+	end = min(max(end, start), count)
+	return start, end
+}
+
+// Position of frame bound, start bound is inclusive and end bound is exclusive
+func (partition *WindowPartition) FrameBound(window WindowExpr, unit TokenType, bound FrameBound, pos int, isStart bool) int {
+	switch bound.Type {
+	case TokenUnboundedPreceding:
+		return 0
+	case TokenUnboundedFollowing:
+		return len(partition.Rows)
+	case TokenCurrentRow:
+		if unit == TokenRows {
+			if isStart {
+				return pos
+			}
+			return pos + 1
+		}
+		if isStart {
+			return partition.PeerStart[pos]
+		}
+		return partition.PeerEnd[pos]
+	}
+
+	if unit == TokenRows {
+		offset, _ := ToInteger(bound.Offset)
+		if bound.Type == TokenPreceding {
+			offset.Int = -offset.Int
+		}
+		if isStart {
+			return pos + int(offset.Int)
+		}
+		return pos + int(offset.Int) + 1
+	}
+	return partition.RangeBound(window, bound, pos, isStart)
+}
+
+// Position of RANGE offset bound: rows whose ORDER BY value is within offset of current value.
+// Current row with NULL value has only its peers in frame
+func (partition *WindowPartition) RangeBound(window WindowExpr, bound FrameBound, pos int, isStart bool) int {
+	current := partition.Keys[pos][0]
+	if current.IsNull() {
+		if isStart {
+			return partition.PeerStart[pos]
+		}
+		return partition.PeerEnd[pos]
+	}
+	// Preceding rows have smaller values in ascending order and larger values in descending order
+	sign := 1
+	if window.OrderBy[0].Direction == TokenDesc {
+		sign = -1
+	}
+	op := TokenPlus
+	if (bound.Type == TokenPreceding) == (sign == 1) {
+		op = TokenMinus
+	}
+	target, err := ArithmeticValues(current, op, bound.Offset)
+	if err != nil {
+		return pos
+	}
+
+	// NULL values are peers of each other at one end of partition, search among non-NULL values
+	low, high := 0, len(partition.Rows)
+	for low < high && partition.Keys[low][0].IsNull() {
+		low++
+	}
+	for high > low && partition.Keys[high-1][0].IsNull() {
+		high--
+	}
+	return low + sort.Search(high-low, func(i int) bool {
+		cmp, _ := CompareValues(partition.Keys[low+i][0], target)
+		if isStart {
+			return cmp*sign >= 0
+		}
+		return cmp*sign > 0
+	})
+}
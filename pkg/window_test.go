@@ -0,0 +1,84 @@
+package pkg
+
+import "testing"
+
+func TestExecuteWindowFrames(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			"SELECT id, SUM(amount) OVER (PARTITION BY region ORDER BY id ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) AS prev, " +
+				"SUM(amount) OVER (ORDER BY id ROWS BETWEEN CURRENT ROW AND 1 FOLLOWING) AS next, " +
+				"COUNT(*) OVER (PARTITION BY region ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS n FROM sales",
+			[][]string{
+				{"id", "prev", "next", "n"},
+				{"1", "10", "30", "3"},
+				{"2", "30", "40", "3"},
+				{"3", "40", "25", "3"},
+				{"4", "5", "20", "3"},
+				{"5", "20", "45", "3"},
+				{"6", "45", "70", "3"},
+				{"7", "40", "40", "1"},
+			},
+		},
+		{
+			// RANGE frames include every peer of current row, ROWS frames stop at current row
+			"SELECT id, amount, SUM(amount) OVER (ORDER BY amount RANGE BETWEEN 5 PRECEDING AND CURRENT ROW) AS preceding, " +
+				"SUM(amount) OVER (ORDER BY amount RANGE BETWEEN CURRENT ROW AND 5 FOLLOWING) AS following, " +
+				"SUM(amount) OVER (ORDER BY amount) AS range_total, " +
+				"SUM(amount) OVER (ORDER BY amount ROWS UNBOUNDED PRECEDING) AS rows_total FROM sales ORDER BY amount, id",
+			[][]string{
+				{"id", "amount", "preceding", "following", "range_total", "rows_total"},
+				{"4", "5", "5", "15", "5", "5"},
+				{"1", "10", "15", "25", "15", "15"},
+				{"5", "15", "25", "55", "30", "30"},
+				{"2", "20", "55", "40", "70", "50"},
+				{"3", "20", "55", "40", "70", "70"},
+				{"6", "30", "30", "30", "100", "100"},
+				{"7", "40", "40", "40", "140", "140"},
+			},
+		},
+		{
+			"SELECT id, FIRST_VALUE(rep) OVER (PARTITION BY region ORDER BY amount DESC) AS top, " +
+				"LAST_VALUE(rep) OVER (PARTITION BY region ORDER BY id ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) AS last FROM sales WHERE region <> 'north'",
+			[][]string{{"id", "top", "last"}, {"1", "Ben", "Ann"}, {"2", "Ben", "Ann"}, {"3", "Ben", "Ann"}, {"4", "Cid", "Cid"}, {"5", "Cid", "Cid"}, {"6", "Cid", "Cid"}},
+		},
+	})
+}
+
+func TestExecuteWindowOffsets(t *testing.T) {
+	runQueryTests(t, []queryTest{
+		{
+			// Missing rows are NULL without default, default is evaluated otherwise
+			"SELECT id, LAG(amount) OVER (ORDER BY id) AS lag1, LAG(amount, 2, 0) OVER (ORDER BY id) AS lag2, " +
+				"LEAD(amount, 1, -1) OVER (ORDER BY id) AS lead1, LEAD(rep) OVER (PARTITION BY region ORDER BY id) AS next_rep FROM sales",
+			[][]string{
+				{"id", "lag1", "lag2", "lead1", "next_rep"},
+				{"1", "", "0", "20", "Ben"},
+				{"2", "10", "0", "20", "Ann"},
+				{"3", "20", "10", "5", ""},
+				{"4", "20", "20", "15", "Dee"},
+				{"5", "5", "20", "30", "Cid"},
+				{"6", "15", "5", "40", ""},
+				{"7", "30", "15", "-1", ""},
+			},
+		},
+		{
+			// First count % n buckets get one more row, more buckets than rows leave buckets empty
+			"SELECT id, NTILE(3) OVER (ORDER BY id) AS n3, NTILE(2) OVER (PARTITION BY region ORDER BY id) AS n2, " +
+				"NTILE(10) OVER (PARTITION BY region ORDER BY id) AS n10 FROM sales",
+			[][]string{
+				{"id", "n3", "n2", "n10"},
+				{"1", "1", "1", "1"},
+				{"2", "1", "1", "2"},
+				{"3", "1", "2", "3"},
+				{"4", "2", "1", "1"},
+				{"5", "2", "1", "2"},
+				{"6", "3", "2", "3"},
+				{"7", "3", "1", "1"},
+			},
+		},
+		{
+			"SELECT id, ROW_NUMBER() OVER (ORDER BY amount, id) AS n, RANK() OVER (ORDER BY amount) AS r, DENSE_RANK() OVER (ORDER BY amount) AS d FROM sales ORDER BY amount, id",
+			[][]string{{"id", "n", "r", "d"}, {"4", "1", "1", "1"}, {"1", "2", "2", "2"}, {"5", "3", "3", "3"}, {"2", "4", "4", "4"}, {"3", "5", "4", "4"}, {"6", "6", "6", "5"}, {"7", "7", "7", "6"}},
+		},
+	})
+}